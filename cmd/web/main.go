@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+	// Embedded zone data keeps todo timezones working on hosts without it
+	_ "time/tzdata"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/config"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/database"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/delivery/http/route"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/hasher"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/mailer"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/notifier"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/usecase"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		log.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("failed to migrate database: %v", err)
+	}
+
+	app, err := newApp(db, cfg)
+	if err != nil {
+		log.Fatalf("failed to initialize application: %v", err)
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "create-admin":
+			if err := runCreateAdmin(app, os.Args[2:]); err != nil {
+				log.Fatalf("create-admin: %v", err)
+			}
+			return
+		default:
+			log.Fatalf("unknown command %q", os.Args[1])
+		}
+	}
+
+	admin, err := app.authUseCase.BootstrapAdmin()
+	if err != nil {
+		log.Fatalf("failed to bootstrap admin: %v", err)
+	}
+	if admin != nil {
+		log.Printf("bootstrapped admin user %q", admin.Username)
+	}
+
+	if cfg.Todo.TrashRetention > 0 {
+		go purgeTrash(app.todoUseCase, cfg.Todo)
+	}
+
+	router := gin.New()
+	// Login throttling keys on the client IP, so only trusted proxies may set it
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
+	route.SetupRoutes(router, app.handler, app.authUseCase, app.apiKeyUseCase, app.roleUseCase, app.auditUseCase)
+
+	log.Printf("server listening on :%s", cfg.Server.Port)
+	if err := router.Run(":" + cfg.Server.Port); err != nil {
+		log.Fatalf("server stopped: %v", err)
+	}
+}
+
+type app struct {
+	authUseCase   usecase.AuthUseCase
+	apiKeyUseCase usecase.APIKeyUseCase
+	roleUseCase   usecase.RoleUseCase
+	auditUseCase  usecase.AuditUseCase
+	todoUseCase   usecase.TodoUseCase
+	handler       *route.Handler
+}
+
+func newApp(db *sql.DB, cfg *config.Config) (*app, error) {
+	userRepo := repository.NewUserRepository(db)
+	todoRepo := repository.NewTodoRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	auditRepo := repository.NewAuditLogRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	projectRepo := repository.NewProjectRepository(db)
+	todoRevisionRepo := repository.NewTodoRevisionRepository(db)
+
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	userNotifier, err := notifier.New(cfg.Notifier, mail)
+	if err != nil {
+		return nil, err
+	}
+
+	var breachedRepo repository.BreachedPasswordRepository
+	if cfg.Password.BreachedListPath != "" {
+		breachedRepo, err = repository.NewFileBreachedPasswordRepository(cfg.Password.BreachedListPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	credentialPolicy := usecase.NewCredentialPolicy(cfg.Password, breachedRepo)
+
+	passwordHasher, err := hasher.New(cfg.Password.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	var attemptRepo repository.LoginAttemptRepository
+	switch cfg.Login.AttemptStore {
+	case "memory":
+		attemptRepo = repository.NewMemoryLoginAttemptRepository()
+	case "postgres":
+		attemptRepo = repository.NewLoginAttemptRepository(db)
+	default:
+		return nil, fmt.Errorf("unknown login attempt store %q", cfg.Login.AttemptStore)
+	}
+
+	userCache := usecase.NewUserCache(userRepo, cfg.Auth.UserCacheTTL)
+	mfaUseCase := usecase.NewMFAUseCase(mfaRepo, userRepo, cfg)
+	auditUseCase := usecase.NewAuditUseCase(auditRepo)
+	emailUseCase := usecase.NewEmailUseCase(userRepo, mail, passwordHasher, cfg)
+	roleUseCase := usecase.NewRoleUseCase(roleRepo, userRepo, userCache)
+	authUseCase := usecase.NewAuthUseCase(userRepo, attemptRepo, sessionRepo, mfaUseCase, auditUseCase, emailUseCase, roleUseCase, credentialPolicy, passwordHasher, userCache, cfg)
+	transactor := repository.NewTransactor(db)
+	todoUseCase := usecase.NewTodoUseCase(todoRepo, tagRepo, projectRepo, todoRevisionRepo, transactor, cfg)
+	userUseCase := usecase.NewUserUseCase(userRepo, sessionRepo, userCache)
+	apiKeyUseCase := usecase.NewAPIKeyUseCase(apiKeyRepo, userRepo)
+	sessionUseCase := usecase.NewSessionUseCase(sessionRepo)
+	tagUseCase := usecase.NewTagUseCase(tagRepo)
+	projectUseCase := usecase.NewProjectUseCase(projectRepo, todoRepo, transactor)
+	passwordUseCase := usecase.NewPasswordUseCase(userRepo, passwordResetRepo, sessionRepo, apiKeyRepo, userNotifier, credentialPolicy, passwordHasher, userCache, cfg)
+
+	return &app{
+		authUseCase:   authUseCase,
+		apiKeyUseCase: apiKeyUseCase,
+		roleUseCase:   roleUseCase,
+		auditUseCase:  auditUseCase,
+		todoUseCase:   todoUseCase,
+		handler:       route.NewHandler(authUseCase, todoUseCase, userUseCase, passwordUseCase, mfaUseCase, apiKeyUseCase, roleUseCase, auditUseCase, sessionUseCase, emailUseCase, tagUseCase, projectUseCase),
+	}, nil
+}
+
+// purgeTrash permanently removes todos kept in the trash for longer than the
+// retention period, at startup and then every purge interval.
+func purgeTrash(todoUseCase usecase.TodoUseCase, cfg config.TodoConfig) {
+	ticker := time.NewTicker(cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := todoUseCase.PurgeTrash(cfg.TrashRetention)
+		if err != nil {
+			log.Printf("failed to purge trash: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d todos from the trash", purged)
+		}
+		<-ticker.C
+	}
+}
+
+// runCreateAdmin implements the create-admin subcommand. The password is read
+// from standard input when -password is omitted so it does not end up in the
+// shell history.
+func runCreateAdmin(app *app, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	username := fs.String("username", "", "username of the admin account")
+	password := fs.String("password", "", "password of the admin account (read from stdin when empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *username == "" {
+		return fmt.Errorf("-username is required")
+	}
+
+	if *password == "" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+
+	user, err := app.authUseCase.CreateAdmin(*username, *password)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("created admin user %q (id %d)", user.Username, user.ID)
+	return nil
+}
@@ -20,11 +20,11 @@ curl -s -X POST $BASE_URL/register \
   -d '{"username": "john", "password": "password123"}' | jq .
 echo
 
-# Register an admin user
-echo "3. Register Admin User:"
+# Register another user
+echo "3. Register Another User:"
 curl -s -X POST $BASE_URL/register \
   -H "Content-Type: application/json" \
-  -d '{"username": "admin", "password": "admin123"}' | jq .
+  -d '{"username": "jane", "password": "password456", "email": "jane@example.com"}' | jq .
 echo
 
 # Login to get token
@@ -70,6 +70,7 @@ echo "9. Update Todo Status:"
 curl -s -X PATCH $BASE_URL/todos/1/status \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $TOKEN" \
+  -H 'If-Match: "1"' \
   -d '{"status": "completed"}' | jq .
 echo
 
@@ -84,16 +85,40 @@ echo "11. Update Todo:"
 curl -s -X PUT $BASE_URL/todos/2 \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $TOKEN" \
+  -H 'If-Match: "1"' \
   -d '{"title": "Complete project ASAP", "description": "Finish the todo app by today"}' | jq .
 echo
 
-echo "=== Admin Examples (need to manually update role in DB) ==="
-echo "To make 'admin' user an admin, run in PostgreSQL:"
-echo "UPDATE users SET role = 'admin' WHERE username = 'admin';"
+# Create an API key for scripts
+echo "12. Create API Key:"
+API_KEY_RESPONSE=$(curl -s -X POST $BASE_URL/profile/api-keys \
+  -H "Content-Type: application/json" \
+  -H "Authorization: Bearer $TOKEN" \
+  -d '{"name": "examples", "scopes": ["todos:read:own"]}')
+
+API_KEY=$(echo $API_KEY_RESPONSE | jq -r '.key')
+echo $API_KEY_RESPONSE | jq .
+echo
+
+# Use the API key instead of a JWT
+echo "13. Get User Todos with API Key:"
+curl -s -X GET $BASE_URL/todos \
+  -H "Authorization: ApiKey $API_KEY" | jq .
+echo
+
+# List the devices this account is logged in on
+echo "13b. List Sessions:"
+curl -s -X GET $BASE_URL/profile/sessions \
+  -H "Authorization: Bearer $TOKEN" | jq .
+echo
+
+echo "=== Admin Examples (need an admin account) ==="
+echo "Create one before running these, e.g.:"
+echo "go run cmd/web/main.go create-admin -username admin -password admin123"
 echo
 
 # Login as admin (after manually setting role)
-echo "12. Login Admin:"
+echo "14. Login Admin:"
 ADMIN_LOGIN_RESPONSE=$(curl -s -X POST $BASE_URL/login \
   -H "Content-Type: application/json" \
   -d '{"username": "admin", "password": "admin123"}')
@@ -103,26 +128,32 @@ echo $ADMIN_LOGIN_RESPONSE | jq .
 echo
 
 # Get all users (admin only)
-echo "13. Get All Users (Admin):"
+echo "15. Get All Users (Admin):"
 curl -s -X GET $BASE_URL/users \
   -H "Authorization: Bearer $ADMIN_TOKEN" | jq .
 echo
 
 # Get all todos (admin only)
-echo "14. Get All Todos (Admin):"
+echo "16. Get All Todos (Admin):"
 curl -s -X GET $BASE_URL/admin/todos \
   -H "Authorization: Bearer $ADMIN_TOKEN" | jq .
 echo
 
+# List roles and their permissions
+echo "16b. List Roles (Admin):"
+curl -s -X GET $BASE_URL/admin/roles \
+  -H "Authorization: Bearer $ADMIN_TOKEN" | jq .
+echo
+
 echo "=== Error Examples ==="
 
 # Try to access without token
-echo "15. Access without token (should fail):"
+echo "17. Access without token (should fail):"
 curl -s -X GET $BASE_URL/todos | jq .
 echo
 
 # Try admin endpoint as regular user
-echo "16. Admin endpoint as regular user (should fail):"
+echo "18. Admin endpoint as regular user (should fail):"
 curl -s -X GET $BASE_URL/users \
   -H "Authorization: Bearer $TOKEN" | jq .
 echo
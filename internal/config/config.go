@@ -4,12 +4,22 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	Database DatabaseConfig
 	JWT      JWTConfig
+	Auth     AuthConfig
 	Server   ServerConfig
+	Admin    AdminConfig
+	Password PasswordConfig
+	Notifier NotifierConfig
+	Mail     MailConfig
+	Login    LoginConfig
+	MFA      MFAConfig
+	Todo     TodoConfig
 }
 
 type DatabaseConfig struct {
@@ -21,13 +31,132 @@ type DatabaseConfig struct {
 	SSLMode  string
 }
 
+// DefaultJWTAudience is the audience of tokens for this API when JWT_AUDIENCE is not set.
+const DefaultJWTAudience = "todo-app-api"
+
+// JWTConfig configures token signing. Issued tokens carry Audience as their
+// aud claim and tokens without it are rejected, so tokens exchanged for
+// another service cannot be replayed against this one.
+// ExchangeAudiences lists the other audiences the token exchange endpoint may
+// issue tokens for.
 type JWTConfig struct {
-	SecretKey string
-	Issuer    string
+	SecretKey         string
+	Issuer            string
+	Audience          string
+	ExchangeAudiences []string
+	ExchangeTTL       time.Duration
+}
+
+// AuthConfig controls how much of a token is trusted. Every request looks
+// the user up (through a cache living UserCacheTTL) to reject revoked tokens
+// and disabled or deleted accounts; with RevalidateUser the stored role and
+// username also replace the ones in the token.
+type AuthConfig struct {
+	RevalidateUser bool
+	UserCacheTTL   time.Duration
+	// GenericRegistrationResponse answers every accepted registration the
+	// same way, so it does not reveal whether a username is taken
+	GenericRegistrationResponse bool
 }
 
+// ServerConfig configures the HTTP listener. TrustedProxies lists the
+// addresses or CIDR ranges allowed to set X-Forwarded-For; with none, the
+// client IP is always the address of the connection.
 type ServerConfig struct {
-	Port string
+	Port           string
+	TrustedProxies []string
+}
+
+// AdminConfig holds the optional credentials used to create the first admin
+// account at startup. Bootstrapping is skipped when either value is empty.
+type AdminConfig struct {
+	BootstrapUsername string
+	BootstrapPassword string
+	// ImpersonationTTL is the lifetime of tokens minted to act as a user
+	ImpersonationTTL time.Duration
+}
+
+type PasswordConfig struct {
+	ResetTokenTTL time.Duration
+
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// BreachedListPath points to a file of SHA-1 hashes of known breached
+	// passwords, one "HASH[:COUNT]" per line. The check is skipped when empty.
+	BreachedListPath string
+
+	UsernameMinLength int
+	UsernameMaxLength int
+
+	Hash PasswordHashConfig
+}
+
+// PasswordHashConfig selects how new password hashes are made. Stored hashes
+// of other algorithms or weaker parameters are upgraded at the next login.
+type PasswordHashConfig struct {
+	Algorithm  string
+	BcryptCost int
+	// Argon2Memory is in KiB
+	Argon2Time    int
+	Argon2Memory  int
+	Argon2Threads int
+}
+
+type NotifierConfig struct {
+	Driver  string
+	FileDir string
+}
+
+// MailConfig selects how email is delivered and how verification links are
+// built. VerificationURL, when set, gets the token appended, e.g.
+// "https://app.example.com/verify-email?token=".
+type MailConfig struct {
+	Driver  string
+	From    string
+	FileDir string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	VerificationTTL time.Duration
+	VerificationURL string
+}
+
+// LoginConfig controls brute-force protection. Failures are counted per
+// username and per client IP; after BackoffAfter failures each further one
+// doubles the wait starting from BackoffBase, and MaxAttempts failures lock
+// the key for LockoutDuration. Counters reset once no failure happened for
+// AttemptWindow.
+type LoginConfig struct {
+	AttemptStore    string
+	MaxAttempts     int
+	BackoffAfter    int
+	BackoffBase     time.Duration
+	LockoutDuration time.Duration
+	AttemptWindow   time.Duration
+}
+
+type MFAConfig struct {
+	// Issuer is the account issuer shown in authenticator apps
+	Issuer          string
+	RequireForAdmin bool
+	ChallengeTTL    time.Duration
+}
+
+// TodoConfig controls the trash and concurrent edits. Deleted todos are
+// purged once they are older than TrashRetention, checked every
+// PurgeInterval; a zero retention keeps them forever. With RequireIfMatch,
+// changing or deleting a todo needs an If-Match header naming its version.
+type TodoConfig struct {
+	TrashRetention time.Duration
+	PurgeInterval  time.Duration
+	RequireIfMatch bool
 }
 
 func Load() (*Config, error) {
@@ -36,7 +165,8 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid DB_PORT: %w", err)
 	}
 
-	return &Config{
+	env := &envReader{}
+	cfg := &Config{
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     dbPort,
@@ -48,11 +178,88 @@ func Load() (*Config, error) {
 		JWT: JWTConfig{
 			SecretKey: getEnv("JWT_SECRET", "your-secret-key"),
 			Issuer:    getEnv("JWT_ISSUER", "todo-app"),
+			Audience:  getEnv("JWT_AUDIENCE", DefaultJWTAudience),
+
+			ExchangeAudiences: env.list("TOKEN_EXCHANGE_AUDIENCES"),
+			ExchangeTTL:       env.duration("TOKEN_EXCHANGE_TTL", 15*time.Minute),
+		},
+		Auth: AuthConfig{
+			RevalidateUser: env.bool("AUTH_REVALIDATE_USER", true),
+			UserCacheTTL:   env.duration("AUTH_USER_CACHE_TTL", 10*time.Second),
+
+			GenericRegistrationResponse: env.bool("REGISTRATION_GENERIC_RESPONSE", false),
 		},
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
+			Port:           getEnv("SERVER_PORT", "8080"),
+			TrustedProxies: env.list("TRUSTED_PROXIES"),
+		},
+		Admin: AdminConfig{
+			BootstrapUsername: os.Getenv("ADMIN_BOOTSTRAP_USERNAME"),
+			BootstrapPassword: os.Getenv("ADMIN_BOOTSTRAP_PASSWORD"),
+			ImpersonationTTL:  env.duration("ADMIN_IMPERSONATION_TTL", 15*time.Minute),
+		},
+		Password: PasswordConfig{
+			ResetTokenTTL:     env.duration("PASSWORD_RESET_TOKEN_TTL", 30*time.Minute),
+			MinLength:         env.int("PASSWORD_MIN_LENGTH", 8),
+			MaxLength:         env.int("PASSWORD_MAX_LENGTH", 72),
+			RequireUpper:      env.bool("PASSWORD_REQUIRE_UPPER", false),
+			RequireLower:      env.bool("PASSWORD_REQUIRE_LOWER", false),
+			RequireDigit:      env.bool("PASSWORD_REQUIRE_DIGIT", false),
+			RequireSymbol:     env.bool("PASSWORD_REQUIRE_SYMBOL", false),
+			BreachedListPath:  os.Getenv("PASSWORD_BREACHED_LIST_PATH"),
+			UsernameMinLength: env.int("USERNAME_MIN_LENGTH", 3),
+			UsernameMaxLength: env.int("USERNAME_MAX_LENGTH", 32),
+			Hash: PasswordHashConfig{
+				Algorithm:     getEnv("PASSWORD_HASH_ALGORITHM", "bcrypt"),
+				BcryptCost:    env.int("PASSWORD_BCRYPT_COST", 10),
+				Argon2Time:    env.int("PASSWORD_ARGON2_TIME", 2),
+				Argon2Memory:  env.int("PASSWORD_ARGON2_MEMORY", 19*1024),
+				Argon2Threads: env.int("PASSWORD_ARGON2_THREADS", 1),
+			},
+		},
+		Notifier: NotifierConfig{
+			Driver:  getEnv("NOTIFIER_DRIVER", "log"),
+			FileDir: getEnv("NOTIFIER_FILE_DIR", "./notifications"),
+		},
+		Mail: MailConfig{
+			Driver:          getEnv("MAIL_DRIVER", "file"),
+			From:            getEnv("MAIL_FROM", "no-reply@localhost"),
+			FileDir:         getEnv("MAIL_FILE_DIR", "./mail"),
+			SMTPHost:        os.Getenv("SMTP_HOST"),
+			SMTPPort:        env.int("SMTP_PORT", 587),
+			SMTPUsername:    os.Getenv("SMTP_USERNAME"),
+			SMTPPassword:    os.Getenv("SMTP_PASSWORD"),
+			VerificationTTL: env.duration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+			VerificationURL: os.Getenv("EMAIL_VERIFICATION_URL"),
+		},
+		Login: LoginConfig{
+			AttemptStore:    getEnv("LOGIN_ATTEMPT_STORE", "memory"),
+			MaxAttempts:     env.int("LOGIN_MAX_ATTEMPTS", 10),
+			BackoffAfter:    env.int("LOGIN_BACKOFF_AFTER", 3),
+			BackoffBase:     env.duration("LOGIN_BACKOFF_BASE", time.Second),
+			LockoutDuration: env.duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			AttemptWindow:   env.duration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+		},
+		MFA: MFAConfig{
+			Issuer:          getEnv("MFA_ISSUER", getEnv("JWT_ISSUER", "todo-app")),
+			RequireForAdmin: env.bool("MFA_REQUIRE_ADMIN", false),
+			ChallengeTTL:    env.duration("MFA_CHALLENGE_TTL", 5*time.Minute),
 		},
-	}, nil
+		Todo: TodoConfig{
+			TrashRetention: env.duration("TODO_TRASH_RETENTION", 30*24*time.Hour),
+			PurgeInterval:  env.duration("TODO_PURGE_INTERVAL", time.Hour),
+			RequireIfMatch: env.bool("TODO_REQUIRE_IF_MATCH", false),
+		},
+	}
+
+	if env.err != nil {
+		return nil, env.err
+	}
+	if cfg.Todo.PurgeInterval <= 0 {
+		return nil, fmt.Errorf("invalid TODO_PURGE_INTERVAL: must be positive")
+	}
+
+	return cfg, nil
 }
 
 func (c *Config) DatabaseURL() string {
@@ -72,3 +279,68 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// envReader parses typed environment variables, keeping the first error so
+// Load can report it once all values have been read.
+type envReader struct {
+	err error
+}
+
+func (r *envReader) int(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		r.fail(key, err)
+		return defaultValue
+	}
+	return parsed
+}
+
+func (r *envReader) bool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		r.fail(key, err)
+		return defaultValue
+	}
+	return parsed
+}
+
+func (r *envReader) duration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		r.fail(key, err)
+		return defaultValue
+	}
+	return parsed
+}
+
+// list splits a comma-separated value, dropping empty items.
+func (r *envReader) list(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (r *envReader) fail(key string, err error) {
+	if r.err == nil {
+		r.err = fmt.Errorf("invalid %s: %w", key, err)
+	}
+}
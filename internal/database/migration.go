@@ -0,0 +1,257 @@
+package database
+
+// migrations are applied in order on startup. Append new statements to the
+// end; never edit or reorder existing ones. Every statement runs on every
+// startup, so seed data must only be written when the row it belongs to is
+// first created, or it would undo changes made since.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS users (
+		id SERIAL PRIMARY KEY,
+		username VARCHAR(255) NOT NULL UNIQUE,
+		password VARCHAR(255) NOT NULL,
+		role VARCHAR(50) NOT NULL DEFAULT 'user',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS todos (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		title VARCHAR(255) NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		status VARCHAR(50) NOT NULL DEFAULT 'pending',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_todos_user_id ON todos(user_id)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE IF NOT EXISTS password_reset_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash VARCHAR(64) NOT NULL UNIQUE,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	// Usernames that differ only in case have to be renamed by hand before
+	// the index can be built; name them rather than failing on the index
+	`DO $$
+	DECLARE
+		duplicates TEXT;
+	BEGIN
+		IF to_regclass('idx_users_username_lower') IS NULL THEN
+			SELECT string_agg(format('%s (ids %s)', usernames, ids), '; ')
+			INTO duplicates
+			FROM (
+				SELECT string_agg(username, ', ' ORDER BY id) AS usernames,
+					string_agg(id::TEXT, ', ' ORDER BY id) AS ids
+				FROM users
+				GROUP BY LOWER(username)
+				HAVING COUNT(*) > 1
+			) AS conflicts;
+			IF duplicates IS NOT NULL THEN
+				RAISE EXCEPTION 'usernames must be unique regardless of case; rename all but one of: %', duplicates;
+			END IF;
+		END IF;
+	END $$`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username))`,
+	`CREATE TABLE IF NOT EXISTS login_attempts (
+		key VARCHAR(320) PRIMARY KEY,
+		failures INTEGER NOT NULL DEFAULT 0,
+		last_failure_at TIMESTAMP NOT NULL,
+		locked_until TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS user_mfa (
+		user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		secret VARCHAR(64) NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT FALSE,
+		last_used_step BIGINT NOT NULL DEFAULT 0,
+		confirmed_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash VARCHAR(64) NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id)`,
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(255) NOT NULL,
+		prefix VARCHAR(16) NOT NULL UNIQUE,
+		key_hash VARCHAR(64) NOT NULL,
+		scopes TEXT[] NOT NULL DEFAULT '{}',
+		expires_at TIMESTAMP,
+		last_used_at TIMESTAMP,
+		revoked_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id)`,
+	`CREATE TABLE IF NOT EXISTS roles (
+		name VARCHAR(50) PRIMARY KEY,
+		description TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS role_permissions (
+		role_name VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+		permission VARCHAR(100) NOT NULL,
+		PRIMARY KEY (role_name, permission)
+	)`,
+	// Built-in roles get their default permissions only when the role itself
+	// is created, so permissions removed through the role editor stay removed
+	`WITH created AS (
+		INSERT INTO roles (name, description) VALUES
+			('user', 'Manages their own todos'),
+			('admin', 'Full access to all users and todos')
+		ON CONFLICT (name) DO NOTHING
+		RETURNING name
+	)
+	INSERT INTO role_permissions (role_name, permission)
+	SELECT defaults.role_name, defaults.permission
+	FROM (VALUES
+		('user', 'profile:read'),
+		('user', 'todos:read:own'),
+		('user', 'todos:write:own'),
+		('admin', 'profile:read'),
+		('admin', 'todos:read:own'),
+		('admin', 'todos:read:any'),
+		('admin', 'todos:write:own'),
+		('admin', 'todos:write:any'),
+		('admin', 'users:read'),
+		('admin', 'users:manage'),
+		('admin', 'roles:manage')
+	) AS defaults (role_name, permission)
+	JOIN created ON created.name = defaults.role_name`,
+	// API key scopes used to be coarse "todos:read"-style names; map them onto
+	// permissions. The owner's role still caps what a key can do.
+	`UPDATE api_keys SET scopes = ARRAY(
+		SELECT DISTINCT unnest(CASE scope
+			WHEN 'todos:read' THEN ARRAY['todos:read:own', 'todos:read:any']
+			WHEN 'todos:write' THEN ARRAY['todos:write:own', 'todos:write:any']
+			ELSE ARRAY[scope]
+		END)
+		FROM unnest(scopes) AS scope
+	)
+	WHERE scopes && ARRAY['todos:read', 'todos:write']::TEXT[]`,
+	`WITH created AS (
+		INSERT INTO roles (name, description) VALUES
+			('support', 'Reads all users and todos without changing them')
+		ON CONFLICT (name) DO NOTHING
+		RETURNING name
+	)
+	INSERT INTO role_permissions (role_name, permission)
+	SELECT defaults.role_name, defaults.permission
+	FROM (VALUES
+		('support', 'profile:read'),
+		('support', 'todos:read:own'),
+		('support', 'todos:read:any'),
+		('support', 'users:read')
+	) AS defaults (role_name, permission)
+	JOIN created ON created.name = defaults.role_name`,
+	// Admins are granted impersonation and audit access once, together with
+	// the audit log they need, so the grant can be revoked afterwards
+	`DO $$
+	BEGIN
+		IF to_regclass('audit_logs') IS NULL THEN
+			CREATE TABLE audit_logs (
+				id SERIAL PRIMARY KEY,
+				actor_user_id INTEGER NOT NULL,
+				action VARCHAR(100) NOT NULL,
+				target_user_id INTEGER,
+				details TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMP NOT NULL DEFAULT NOW()
+			);
+			INSERT INTO role_permissions (role_name, permission) VALUES
+				('admin', 'users:impersonate'),
+				('admin', 'audit:read')
+			ON CONFLICT DO NOTHING;
+		END IF;
+	END $$`,
+	`CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at)`,
+	`CREATE TABLE IF NOT EXISTS sessions (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		ip_address VARCHAR(64) NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(254)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email))`,
+	// Statuses outside the state machine were accepted before it existed
+	`UPDATE todos SET status = 'pending'
+	WHERE status NOT IN ('pending', 'in_progress', 'blocked', 'completed', 'cancelled', 'archived')`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'todos_status_check') THEN
+			ALTER TABLE todos ADD CONSTRAINT todos_status_check
+				CHECK (status IN ('pending', 'in_progress', 'blocked', 'completed', 'cancelled', 'archived'));
+		END IF;
+	END $$`,
+	`ALTER TABLE todos ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP`,
+	`UPDATE todos SET completed_at = updated_at WHERE status = 'completed' AND completed_at IS NULL`,
+	`CREATE TABLE IF NOT EXISTS tags (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(50) NOT NULL,
+		color VARCHAR(7) NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_id_name_lower ON tags (user_id, LOWER(name))`,
+	`CREATE TABLE IF NOT EXISTS todo_tags (
+		todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+		tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+		PRIMARY KEY (todo_id, tag_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_todo_tags_tag_id ON todo_tags(tag_id)`,
+	`CREATE TABLE IF NOT EXISTS projects (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(100) NOT NULL,
+		color VARCHAR(7) NOT NULL DEFAULT '',
+		archived BOOLEAN NOT NULL DEFAULT FALSE,
+		position INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_projects_user_id ON projects(user_id)`,
+	`ALTER TABLE todos ADD COLUMN IF NOT EXISTS project_id INTEGER REFERENCES projects(id) ON DELETE SET NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_todos_project_id ON todos(project_id)`,
+	`ALTER TABLE todos ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES todos(id) ON DELETE CASCADE`,
+	`ALTER TABLE todos ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0`,
+	`CREATE INDEX IF NOT EXISTS idx_todos_parent_id ON todos(parent_id)`,
+	`ALTER TABLE todos ADD COLUMN IF NOT EXISTS due_at TIMESTAMPTZ`,
+	`ALTER TABLE todos ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC'`,
+	`ALTER TABLE todos ADD COLUMN IF NOT EXISTS recurrence TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE todos ADD COLUMN IF NOT EXISTS occurrence INTEGER NOT NULL DEFAULT 1`,
+	// Titles weigh more than descriptions when ranking search results
+	`ALTER TABLE todos ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+			setweight(to_tsvector('english', COALESCE(description, '')), 'B')
+		) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_todos_search_vector ON todos USING GIN (search_vector)`,
+	`ALTER TABLE todos ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+	`CREATE INDEX IF NOT EXISTS idx_todos_deleted_at ON todos(deleted_at) WHERE deleted_at IS NOT NULL`,
+	`CREATE TABLE IF NOT EXISTS todo_revisions (
+		id SERIAL PRIMARY KEY,
+		todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+		actor_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		impersonator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		admin_action BOOLEAN NOT NULL DEFAULT FALSE,
+		action VARCHAR(20) NOT NULL,
+		changes JSONB NOT NULL DEFAULT '[]',
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_todo_revisions_todo_id ON todo_revisions(todo_id, created_at)`,
+	`ALTER TABLE todos ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/delivery/http/middleware"
@@ -11,11 +12,13 @@ import (
 
 type AuthHandler struct {
 	authUseCase usecase.AuthUseCase
+	userUseCase usecase.UserUseCase
 }
 
-func NewAuthHandler(authUseCase usecase.AuthUseCase) *AuthHandler {
+func NewAuthHandler(authUseCase usecase.AuthUseCase, userUseCase usecase.UserUseCase) *AuthHandler {
 	return &AuthHandler{
 		authUseCase: authUseCase,
+		userUseCase: userUseCase,
 	}
 }
 
@@ -28,7 +31,16 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	user, err := h.authUseCase.Register(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Generic mode: the same answer whether or not the username was taken
+	if user == nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Registration received. If the username and email were available, you can now log in",
+			"status":  "success",
+		})
 		return
 	}
 
@@ -46,9 +58,45 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	req.ClientIP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
 	response, err := h.authUseCase.Login(&req)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	if response.MFARequired {
+		c.JSON(http.StatusOK, gin.H{
+			"message":                 "MFA required",
+			"mfa_required":            true,
+			"mfa_enrollment_required": response.MFAEnrollmentRequired,
+			"mfa_token":               response.MFAToken,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+		"token":   response.Token,
+		"user":    response.User,
+	})
+}
+
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req model.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.ClientIP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	response, err := h.authUseCase.LoginWithMFA(&req)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -59,6 +107,49 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+func (h *AuthHandler) LoginMFAEnroll(c *gin.Context) {
+	var req model.MFAEnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, err := h.authUseCase.EnrollMFAForLogin(&req)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Scan the URI with an authenticator app and confirm with a code",
+		"enrollment": enrollment,
+	})
+}
+
+func (h *AuthHandler) LoginMFAConfirm(c *gin.Context) {
+	var req model.MFAConfirmLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.ClientIP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	response, err := h.authUseCase.ConfirmMFAForLogin(&req)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "MFA enabled, login successful",
+		"token":          response.Token,
+		"user":           response.User,
+		"recovery_codes": response.RecoveryCodes,
+	})
+}
+
 func (h *AuthHandler) GetProfile(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
@@ -66,14 +157,70 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	username, _ := middleware.GetUsername(c)
-	role, _ := middleware.GetUserRole(c)
+	// Read the stored profile; the token's claims may be out of date
+	user, err := h.userUseCase.GetByID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"user": gin.H{
-			"id":       userID,
-			"username": username,
-			"role":     role,
-		},
-	})
+	response := gin.H{
+		"user":        user,
+		"permissions": middleware.GetPermissions(c).List(),
+	}
+	if impersonatorID, impersonatorUsername, ok := middleware.GetImpersonator(c); ok {
+		response["impersonated_by"] = gin.H{
+			"id":       impersonatorID,
+			"username": impersonatorUsername,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *AuthHandler) Impersonate(c *gin.Context) {
+	targetUserID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	actor, exists := middleware.GetActor(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var req model.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authUseCase.Impersonate(actor, targetUserID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ExchangeToken implements the RFC 8693 token exchange. The subject token is
+// passed in the body, so the route needs no Authorization header.
+func (h *AuthHandler) ExchangeToken(c *gin.Context) {
+	var req model.TokenExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authUseCase.ExchangeToken(&req)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, response)
 }
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/delivery/http/middleware"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/usecase"
+)
+
+type EmailHandler struct {
+	emailUseCase usecase.EmailUseCase
+}
+
+func NewEmailHandler(emailUseCase usecase.EmailUseCase) *EmailHandler {
+	return &EmailHandler{
+		emailUseCase: emailUseCase,
+	}
+}
+
+func (h *EmailHandler) ChangeEmail(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var req model.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.emailUseCase.ChangeEmail(userID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email updated, check your inbox to verify it",
+		"user":    user,
+	})
+}
+
+func (h *EmailHandler) ResendVerification(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	if err := h.emailUseCase.SendVerification(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If your email is not verified yet, a new verification link has been sent",
+	})
+}
+
+func (h *EmailHandler) Verify(c *gin.Context) {
+	var req model.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.emailUseCase.Verify(&req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified successfully",
+		"user":    user,
+	})
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/usecase"
+)
+
+// respondError writes err as a JSON error response. Errors the use cases
+// report with a dedicated type get their own status code and payload;
+// anything else is sent with the given fallback status.
+func respondError(c *gin.Context, status int, err error) {
+	var preconditionErr *usecase.PreconditionFailedError
+	if errors.As(err, &preconditionErr) && preconditionErr.Version != 0 {
+		c.Header("ETag", todoETag(preconditionErr.Version))
+	}
+
+	var tooManyAttemptsErr *usecase.TooManyAttemptsError
+	if errors.As(err, &tooManyAttemptsErr) {
+		c.Header("Retry-After", strconv.Itoa(retryAfterSeconds(tooManyAttemptsErr)))
+	}
+
+	status, body := errorResponse(status, err)
+	c.JSON(status, body)
+}
+
+// errorResponse maps err to a status code and JSON payload, falling back to
+// status. It sets no headers, so it also serves errors nested in a response,
+// such as the results of a bulk request.
+func errorResponse(status int, err error) (int, gin.H) {
+	var validationErr *usecase.ValidationError
+	if errors.As(err, &validationErr) {
+		return http.StatusBadRequest, gin.H{
+			"error":  "validation failed",
+			"fields": validationErr.Fields,
+		}
+	}
+
+	var forbiddenErr *usecase.ForbiddenError
+	if errors.As(err, &forbiddenErr) {
+		return http.StatusForbidden, gin.H{"error": forbiddenErr.Error()}
+	}
+
+	if errors.Is(err, usecase.ErrAccountDisabled) {
+		return http.StatusForbidden, gin.H{"error": err.Error()}
+	}
+
+	var transitionErr *usecase.InvalidTransitionError
+	if errors.As(err, &transitionErr) {
+		return http.StatusUnprocessableEntity, gin.H{
+			"error":   transitionErr.Error(),
+			"status":  transitionErr.From,
+			"allowed": transitionErr.Allowed,
+		}
+	}
+
+	var conflictErr *usecase.ConflictError
+	if errors.As(err, &conflictErr) {
+		return http.StatusConflict, gin.H{
+			"error":      conflictErr.Error(),
+			"open_todos": conflictErr.OpenTodos,
+		}
+	}
+
+	var preconditionErr *usecase.PreconditionFailedError
+	if errors.As(err, &preconditionErr) {
+		return http.StatusPreconditionFailed, gin.H{"error": preconditionErr.Error()}
+	}
+
+	if errors.Is(err, usecase.ErrPreconditionRequired) {
+		return http.StatusPreconditionRequired, gin.H{"error": err.Error()}
+	}
+
+	var tooManyAttemptsErr *usecase.TooManyAttemptsError
+	if errors.As(err, &tooManyAttemptsErr) {
+		return http.StatusTooManyRequests, gin.H{
+			"error":       tooManyAttemptsErr.Error(),
+			"retry_after": retryAfterSeconds(tooManyAttemptsErr),
+		}
+	}
+
+	return status, gin.H{"error": err.Error()}
+}
+
+func retryAfterSeconds(err *usecase.TooManyAttemptsError) int {
+	return int(math.Ceil(err.RetryAfter.Seconds()))
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/usecase"
+)
+
+// AuditImpersonation records every request that changes data while an admin
+// is impersonating a user. Reads are only visible in traces.
+func AuditImpersonation(auditUseCase usecase.AuditUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		impersonatorID, _, impersonated := GetImpersonator(c)
+		if !impersonated {
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+
+		userID, _ := GetUserID(c)
+		err := auditUseCase.Record(&entity.AuditLog{
+			ActorUserID:  impersonatorID,
+			Action:       entity.AuditActionImpersonationRequest,
+			TargetUserID: userID,
+			Details:      fmt.Sprintf("%s %s -> %d", c.Request.Method, c.Request.URL.Path, c.Writer.Status()),
+		})
+		if err != nil {
+			log.Printf("audit: %v", err)
+		}
+	}
+}
@@ -5,17 +5,35 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/usecase"
 )
 
 const (
-	AuthUserID   = "user_id"
-	AuthUsername = "username"
-	AuthRole     = "role"
+	AuthUserID      = "user_id"
+	AuthUsername    = "username"
+	AuthRole        = "role"
+	AuthPermissions = "permissions"
+	// AuthAPIKeyID is only set for requests authenticated with an API key
+	AuthAPIKeyID = "api_key_id"
+	// AuthImpersonatorID and AuthImpersonatorUsername are only set when an
+	// admin acts as the user with an impersonation token
+	AuthImpersonatorID       = "impersonator_id"
+	AuthImpersonatorUsername = "impersonator_username"
+	// AuthSessionID is set for bearer tokens issued at login
+	AuthSessionID = "session_id"
+	// AuthTokenScope is only set for bearer tokens limited by token exchange
+	AuthTokenScope = "token_scope"
 )
 
-func JWTAuth(authUseCase usecase.AuthUseCase) gin.HandlerFunc {
+// JWTAuth authenticates requests with either "Bearer <jwt>" or
+// "ApiKey <key>" in the Authorization header and resolves the caller's
+// permissions from their role. API key requests and exchanged tokens are
+// further limited to their scopes.
+func JWTAuth(authUseCase usecase.AuthUseCase, apiKeyUseCase usecase.APIKeyUseCase, roleUseCase usecase.RoleUseCase) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -24,50 +42,141 @@ func JWTAuth(authUseCase usecase.AuthUseCase) gin.HandlerFunc {
 			return
 		}
 
-		// Check if header starts with "Bearer "
-		if !strings.HasPrefix(authHeader, "Bearer ") {
+		var scopes entity.PermissionSet
+
+		switch {
+		case strings.HasPrefix(authHeader, "ApiKey "):
+			user, apiKey, err := apiKeyUseCase.Authenticate(strings.TrimPrefix(authHeader, "ApiKey "))
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+				c.Abort()
+				return
+			}
+
+			c.Set(AuthUserID, user.ID)
+			c.Set(AuthUsername, user.Username)
+			c.Set(AuthRole, user.Role)
+			c.Set(AuthAPIKeyID, apiKey.ID)
+			scopes = apiKey.ScopeSet()
+
+		case strings.HasPrefix(authHeader, "Bearer "):
+			// Extract token
+			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+			if tokenString == "" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token required"})
+				c.Abort()
+				return
+			}
+
+			// Verify token
+			claims, err := authUseCase.VerifyToken(tokenString)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				c.Abort()
+				return
+			}
+
+			// Set user info in context
+			c.Set(AuthUserID, claims.UserID)
+			c.Set(AuthUsername, claims.Username)
+			c.Set(AuthRole, claims.Role)
+			if claims.SessionID != 0 {
+				c.Set(AuthSessionID, claims.SessionID)
+			}
+			if claims.Act != nil {
+				c.Set(AuthImpersonatorID, claims.Act.UserID)
+				c.Set(AuthImpersonatorUsername, claims.Act.Username)
+			}
+			if claims.Scope != "" {
+				c.Set(AuthTokenScope, claims.Scope)
+				scopes = claims.Scopes()
+			}
+
+		default:
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
 			c.Abort()
 			return
 		}
 
-		// Extract token
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token required"})
+		role, _ := GetUserRole(c)
+		permissions, err := roleUseCase.PermissionsFor(role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve permissions"})
 			c.Abort()
 			return
 		}
+		if scopes != nil {
+			permissions = permissions.Intersect(scopes)
+		}
+		c.Set(AuthPermissions, permissions)
 
-		// Verify token
-		claims, err := authUseCase.VerifyToken(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		setTraceAttributes(c)
+
+		c.Next()
+	}
+}
+
+// setTraceAttributes tags the current span with the caller so traces of
+// impersonated requests can be told apart from the user's own.
+func setTraceAttributes(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	if !span.IsRecording() {
+		return
+	}
+
+	userID, _ := GetUserID(c)
+	attrs := []attribute.KeyValue{attribute.Int("enduser.id", userID)}
+	if impersonatorID, _, ok := GetImpersonator(c); ok {
+		attrs = append(attrs,
+			attribute.Bool("auth.impersonated", true),
+			attribute.Int("auth.impersonator.id", impersonatorID),
+		)
+	}
+	span.SetAttributes(attrs...)
+}
+
+// RequirePermission allows the request when the caller holds at least one of
+// permissions.
+func RequirePermission(permissions ...entity.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := GetPermissions(c)
+		if !granted.HasAny(permissions...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission " + string(permissions[0]) + " required"})
 			c.Abort()
 			return
 		}
 
-		// Set user info in context
-		c.Set(AuthUserID, claims.UserID)
-		c.Set(AuthUsername, claims.Username)
-		c.Set(AuthRole, claims.Role)
-
 		c.Next()
 	}
 }
 
-func AdminOnly() gin.HandlerFunc {
+// SessionOnly rejects requests authenticated with an API key or an exchanged,
+// scoped token. It guards account management that a leaked script or service
+// credential must not reach.
+func SessionOnly() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get(AuthRole)
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "No role found in context"})
+		if _, viaAPIKey := c.Get(AuthAPIKeyID); viaAPIKey {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint cannot be used with an API key"})
+			c.Abort()
+			return
+		}
+		if _, scoped := c.Get(AuthTokenScope); scoped {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint cannot be used with a scoped token"})
 			c.Abort()
 			return
 		}
 
-		userRole, ok := role.(entity.Role)
-		if !ok || userRole != entity.AdminRole {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		c.Next()
+	}
+}
+
+// NoImpersonation rejects requests made with an impersonation token. It
+// guards operations an admin must not perform in the user's name, such as
+// changing their password.
+func NoImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, _, impersonated := GetImpersonator(c); impersonated {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint cannot be used while impersonating"})
 			c.Abort()
 			return
 		}
@@ -107,7 +216,56 @@ func GetUserRole(c *gin.Context) (entity.Role, bool) {
 	return userRole, ok
 }
 
-func IsAdmin(c *gin.Context) bool {
-	role, ok := GetUserRole(c)
-	return ok && role == entity.AdminRole
+// GetSessionID returns the login session of a bearer token.
+func GetSessionID(c *gin.Context) (int, bool) {
+	sessionID, exists := c.Get(AuthSessionID)
+	if !exists {
+		return 0, false
+	}
+
+	id, ok := sessionID.(int)
+	return id, ok
+}
+
+// GetImpersonator returns the admin acting as the user, if any.
+func GetImpersonator(c *gin.Context) (int, string, bool) {
+	impersonatorID, exists := c.Get(AuthImpersonatorID)
+	if !exists {
+		return 0, "", false
+	}
+
+	id, ok := impersonatorID.(int)
+	username := c.GetString(AuthImpersonatorUsername)
+	return id, username, ok
+}
+
+// GetPermissions returns the caller's effective permissions, or an empty set
+// when the request is not authenticated.
+func GetPermissions(c *gin.Context) entity.PermissionSet {
+	permissions, exists := c.Get(AuthPermissions)
+	if !exists {
+		return entity.NewPermissionSet()
+	}
+
+	set, ok := permissions.(entity.PermissionSet)
+	if !ok {
+		return entity.NewPermissionSet()
+	}
+	return set
+}
+
+// GetActor returns the caller as passed to use cases.
+func GetActor(c *gin.Context) (*entity.Actor, bool) {
+	userID, exists := GetUserID(c)
+	if !exists {
+		return nil, false
+	}
+
+	impersonatorID, _, _ := GetImpersonator(c)
+
+	return &entity.Actor{
+		UserID:         userID,
+		Permissions:    GetPermissions(c),
+		ImpersonatorID: impersonatorID,
+	}, true
 }
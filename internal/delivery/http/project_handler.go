@@ -0,0 +1,200 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/delivery/http/middleware"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/usecase"
+)
+
+type ProjectHandler struct {
+	projectUseCase usecase.ProjectUseCase
+}
+
+func NewProjectHandler(projectUseCase usecase.ProjectUseCase) *ProjectHandler {
+	return &ProjectHandler{
+		projectUseCase: projectUseCase,
+	}
+}
+
+func (h *ProjectHandler) List(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	includeArchived := c.Query("include_archived") == "true"
+	projects, err := h.projectUseCase.GetByUserID(userID, includeArchived)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"projects": projects,
+	})
+}
+
+func (h *ProjectHandler) Create(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var req model.CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.projectUseCase.Create(userID, &req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Project created successfully",
+		"project": project,
+	})
+}
+
+func (h *ProjectHandler) GetByID(c *gin.Context) {
+	projectID, userID, ok := projectParams(c)
+	if !ok {
+		return
+	}
+
+	project, err := h.projectUseCase.GetByID(projectID, userID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"project": project,
+	})
+}
+
+func (h *ProjectHandler) GetTodos(c *gin.Context) {
+	projectID, userID, ok := projectParams(c)
+	if !ok {
+		return
+	}
+
+	todos, err := h.projectUseCase.GetTodos(projectID, userID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"todos": todos,
+	})
+}
+
+func (h *ProjectHandler) Update(c *gin.Context) {
+	projectID, userID, ok := projectParams(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.projectUseCase.Update(projectID, userID, &req)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Project updated successfully",
+		"project": project,
+	})
+}
+
+func (h *ProjectHandler) Delete(c *gin.Context) {
+	projectID, userID, ok := projectParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.projectUseCase.Delete(projectID, userID); err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Project deleted successfully",
+	})
+}
+
+func (h *ProjectHandler) Archive(c *gin.Context) {
+	projectID, _, ok := projectParams(c)
+	if !ok {
+		return
+	}
+
+	actor, exists := middleware.GetActor(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	force := c.Query("force") == "true"
+	project, err := h.projectUseCase.Archive(projectID, actor, force)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Project archived successfully",
+		"project": project,
+	})
+}
+
+func (h *ProjectHandler) Unarchive(c *gin.Context) {
+	projectID, userID, ok := projectParams(c)
+	if !ok {
+		return
+	}
+
+	project, err := h.projectUseCase.Unarchive(projectID, userID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Project unarchived successfully",
+		"project": project,
+	})
+}
+
+// projectParams reads the project ID from the path and the caller from the
+// context, writing the error response when either is missing.
+func projectParams(c *gin.Context) (int, int, bool) {
+	projectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return 0, 0, false
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return 0, 0, false
+	}
+
+	return projectID, userID, true
+}
@@ -4,28 +4,64 @@ import (
 	"github.com/gin-gonic/gin"
 	httpHandler "github.com/islamyakin/otel-propagation-monorepo/internal/delivery/http"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/delivery/http/middleware"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/metrics"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/usecase"
 )
 
 type Handler struct {
-	AuthHandler *httpHandler.AuthHandler
-	TodoHandler *httpHandler.TodoHandler
-	UserHandler *httpHandler.UserHandler
+	AuthHandler     *httpHandler.AuthHandler
+	TodoHandler     *httpHandler.TodoHandler
+	UserHandler     *httpHandler.UserHandler
+	PasswordHandler *httpHandler.PasswordHandler
+	MFAHandler      *httpHandler.MFAHandler
+	APIKeyHandler   *httpHandler.APIKeyHandler
+	RoleHandler     *httpHandler.RoleHandler
+	AuditHandler    *httpHandler.AuditHandler
+	SessionHandler  *httpHandler.SessionHandler
+	EmailHandler    *httpHandler.EmailHandler
+	TagHandler      *httpHandler.TagHandler
+	ProjectHandler  *httpHandler.ProjectHandler
 }
 
 func NewHandler(
 	authUseCase usecase.AuthUseCase,
 	todoUseCase usecase.TodoUseCase,
 	userUseCase usecase.UserUseCase,
+	passwordUseCase usecase.PasswordUseCase,
+	mfaUseCase usecase.MFAUseCase,
+	apiKeyUseCase usecase.APIKeyUseCase,
+	roleUseCase usecase.RoleUseCase,
+	auditUseCase usecase.AuditUseCase,
+	sessionUseCase usecase.SessionUseCase,
+	emailUseCase usecase.EmailUseCase,
+	tagUseCase usecase.TagUseCase,
+	projectUseCase usecase.ProjectUseCase,
 ) *Handler {
 	return &Handler{
-		AuthHandler: httpHandler.NewAuthHandler(authUseCase),
-		TodoHandler: httpHandler.NewTodoHandler(todoUseCase),
-		UserHandler: httpHandler.NewUserHandler(userUseCase),
+		AuthHandler:     httpHandler.NewAuthHandler(authUseCase, userUseCase),
+		TodoHandler:     httpHandler.NewTodoHandler(todoUseCase),
+		UserHandler:     httpHandler.NewUserHandler(userUseCase),
+		PasswordHandler: httpHandler.NewPasswordHandler(passwordUseCase),
+		MFAHandler:      httpHandler.NewMFAHandler(mfaUseCase),
+		APIKeyHandler:   httpHandler.NewAPIKeyHandler(apiKeyUseCase),
+		RoleHandler:     httpHandler.NewRoleHandler(roleUseCase),
+		AuditHandler:    httpHandler.NewAuditHandler(auditUseCase),
+		SessionHandler:  httpHandler.NewSessionHandler(sessionUseCase),
+		EmailHandler:    httpHandler.NewEmailHandler(emailUseCase),
+		TagHandler:      httpHandler.NewTagHandler(tagUseCase),
+		ProjectHandler:  httpHandler.NewProjectHandler(projectUseCase),
 	}
 }
 
-func SetupRoutes(router *gin.Engine, handler *Handler, authUseCase usecase.AuthUseCase) {
+func SetupRoutes(
+	router *gin.Engine,
+	handler *Handler,
+	authUseCase usecase.AuthUseCase,
+	apiKeyUseCase usecase.APIKeyUseCase,
+	roleUseCase usecase.RoleUseCase,
+	auditUseCase usecase.AuditUseCase,
+) {
 	// Middleware
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
@@ -41,31 +77,129 @@ func SetupRoutes(router *gin.Engine, handler *Handler, authUseCase usecase.AuthU
 		// Auth routes (public)
 		v1.POST("/register", handler.AuthHandler.Register)
 		v1.POST("/login", handler.AuthHandler.Login)
+		v1.POST("/login/mfa", handler.AuthHandler.LoginMFA)
+		v1.POST("/login/mfa/enroll", handler.AuthHandler.LoginMFAEnroll)
+		v1.POST("/login/mfa/confirm", handler.AuthHandler.LoginMFAConfirm)
+		v1.POST("/password/forgot", handler.PasswordHandler.ForgotPassword)
+		v1.POST("/password/reset", handler.PasswordHandler.ResetPassword)
+		v1.POST("/email/verify", handler.EmailHandler.Verify)
+		v1.POST("/token/exchange", handler.AuthHandler.ExchangeToken)
 
-		// Protected routes
+		// Protected routes. Every route states the permission it needs; API
+		// key requests only hold the permissions granted to the key.
 		protected := v1.Group("")
-		protected.Use(middleware.JWTAuth(authUseCase))
+		protected.Use(middleware.JWTAuth(authUseCase, apiKeyUseCase, roleUseCase))
+		protected.Use(middleware.AuditImpersonation(auditUseCase))
 		{
+			readProfile := middleware.RequirePermission(entity.PermissionProfileRead)
+			readTodos := middleware.RequirePermission(entity.PermissionTodosReadOwn, entity.PermissionTodosReadAny)
+			writeTodos := middleware.RequirePermission(entity.PermissionTodosWriteOwn, entity.PermissionTodosWriteAny)
+
 			// User profile
-			protected.GET("/profile", handler.AuthHandler.GetProfile)
-
-			// Todo routes for users
-			protected.POST("/todos", handler.TodoHandler.Create)
-			protected.GET("/todos", handler.TodoHandler.GetUserTodos)
-			protected.GET("/todos/:id", handler.TodoHandler.GetByID)
-			protected.PUT("/todos/:id", handler.TodoHandler.Update)
-			protected.DELETE("/todos/:id", handler.TodoHandler.Delete)
-			protected.PATCH("/todos/:id/status", handler.TodoHandler.UpdateStatus)
-
-			// Admin only routes
-			admin := protected.Group("")
-			admin.Use(middleware.AdminOnly())
+			protected.GET("/profile", readProfile, handler.AuthHandler.GetProfile)
+
+			// Account security, not reachable with an API key or while an
+			// admin impersonates the user
+			session := protected.Group("")
+			session.Use(middleware.SessionOnly(), middleware.NoImpersonation())
 			{
-				// Admin can see all users
-				admin.GET("/users", handler.UserHandler.GetAllUsers)
+				session.PUT("/profile/password", handler.PasswordHandler.ChangePassword)
+				session.PUT("/profile/email", handler.EmailHandler.ChangeEmail)
+				session.POST("/profile/email/verification", handler.EmailHandler.ResendVerification)
+				session.POST("/profile/mfa/enroll", handler.MFAHandler.Enroll)
+				session.POST("/profile/mfa/confirm", handler.MFAHandler.Confirm)
+				session.DELETE("/profile/mfa", handler.MFAHandler.Disable)
+				session.POST("/profile/api-keys", handler.APIKeyHandler.Create)
+				session.GET("/profile/api-keys", handler.APIKeyHandler.List)
+				session.DELETE("/profile/api-keys/:id", handler.APIKeyHandler.Revoke)
+				session.GET("/profile/sessions", handler.SessionHandler.List)
+				session.DELETE("/profile/sessions/:id", handler.SessionHandler.Revoke)
+			}
+
+			// Todo routes; ownership is checked by the use case
+			protected.POST("/todos", middleware.RequirePermission(entity.PermissionTodosWriteOwn), handler.TodoHandler.Create)
+			protected.GET("/todos", middleware.RequirePermission(entity.PermissionTodosReadOwn), handler.TodoHandler.GetUserTodos)
+			protected.POST("/todos/bulk", writeTodos, handler.TodoHandler.Bulk)
+			protected.GET("/todos/trash", middleware.RequirePermission(entity.PermissionTodosReadOwn), handler.TodoHandler.GetTrash)
+			protected.GET("/todos/search", middleware.RequirePermission(entity.PermissionTodosReadOwn), handler.TodoHandler.Search)
+			protected.GET("/todos/:id", readTodos, handler.TodoHandler.GetByID)
+			protected.GET("/todos/:id/occurrences", readTodos, handler.TodoHandler.Occurrences)
+			protected.GET("/todos/:id/history", readTodos, handler.TodoHandler.GetHistory)
+			protected.PUT("/todos/:id", writeTodos, handler.TodoHandler.Update)
+			protected.DELETE("/todos/:id", writeTodos, handler.TodoHandler.Delete)
+			protected.PATCH("/todos/:id/status", writeTodos, handler.TodoHandler.UpdateStatus)
+			protected.POST("/todos/:id/restore", writeTodos, handler.TodoHandler.Restore)
+			protected.POST("/todos/:id/tags", writeTodos, handler.TodoHandler.AttachTag)
+			protected.DELETE("/todos/:id/tags/:tagId", writeTodos, handler.TodoHandler.DetachTag)
+
+			// Tags belong to the caller
+			protected.GET("/tags", middleware.RequirePermission(entity.PermissionTodosReadOwn), handler.TagHandler.List)
+			protected.POST("/tags", middleware.RequirePermission(entity.PermissionTodosWriteOwn), handler.TagHandler.Create)
+			protected.PUT("/tags/:id", middleware.RequirePermission(entity.PermissionTodosWriteOwn), handler.TagHandler.Update)
+			protected.DELETE("/tags/:id", middleware.RequirePermission(entity.PermissionTodosWriteOwn), handler.TagHandler.Delete)
 
-				// Admin can see all todos
-				admin.GET("/admin/todos", handler.TodoHandler.GetAllTodos)
+			// Projects belong to the caller
+			projects := protected.Group("/projects")
+			{
+				readOwn := middleware.RequirePermission(entity.PermissionTodosReadOwn)
+				writeOwn := middleware.RequirePermission(entity.PermissionTodosWriteOwn)
+
+				projects.GET("", readOwn, handler.ProjectHandler.List)
+				projects.POST("", writeOwn, handler.ProjectHandler.Create)
+				projects.GET("/:id", readOwn, handler.ProjectHandler.GetByID)
+				projects.GET("/:id/todos", readOwn, handler.ProjectHandler.GetTodos)
+				projects.PUT("/:id", writeOwn, handler.ProjectHandler.Update)
+				projects.DELETE("/:id", writeOwn, handler.ProjectHandler.Delete)
+				projects.POST("/:id/archive", writeOwn, handler.ProjectHandler.Archive)
+				projects.POST("/:id/unarchive", writeOwn, handler.ProjectHandler.Unarchive)
+			}
+
+			// Administration, read-only. Support staff hold these permissions
+			// without any of the write permissions below.
+			adminRead := protected.Group("/admin")
+			{
+				readUsers := middleware.RequirePermission(entity.PermissionUsersRead)
+				readAnyTodo := middleware.RequirePermission(entity.PermissionTodosReadAny)
+
+				adminRead.GET("/users", readUsers, handler.UserHandler.GetAllUsers)
+				adminRead.GET("/users/:id", readUsers, handler.UserHandler.GetByID)
+				adminRead.GET("/todos", readAnyTodo, handler.TodoHandler.GetAllTodos)
+				adminRead.GET("/todos/search", readAnyTodo, handler.TodoHandler.SearchAll)
+				adminRead.GET("/todos/:id", readAnyTodo, handler.TodoHandler.GetByID)
+				adminRead.GET("/todos/:id/history", readAnyTodo, handler.TodoHandler.GetHistory)
+				adminRead.GET("/audit-logs", middleware.RequirePermission(entity.PermissionAuditRead), handler.AuditHandler.GetRecent)
+				// Login protection counters such as lockouts
+				adminRead.GET("/metrics", middleware.RequirePermission(entity.PermissionAuditRead), gin.WrapH(metrics.Handler()))
+			}
+
+			// Administration, changes
+			adminWrite := protected.Group("/admin")
+			adminWrite.Use(middleware.NoImpersonation())
+			{
+				writeAnyTodo := middleware.RequirePermission(entity.PermissionTodosWriteAny)
+
+				manageUsers := middleware.RequirePermission(entity.PermissionUsersManage)
+
+				adminWrite.PUT("/users/:id/role", manageUsers, handler.RoleHandler.AssignRole)
+				adminWrite.POST("/users/:id/disable", manageUsers, handler.UserHandler.Disable)
+				adminWrite.POST("/users/:id/enable", manageUsers, handler.UserHandler.Enable)
+				adminWrite.PUT("/todos/:id", writeAnyTodo, handler.TodoHandler.Update)
+				adminWrite.PATCH("/todos/:id/status", writeAnyTodo, handler.TodoHandler.UpdateStatus)
+				adminWrite.DELETE("/todos/:id", writeAnyTodo, handler.TodoHandler.Delete)
+				adminWrite.POST("/todos/:id/restore", writeAnyTodo, handler.TodoHandler.Restore)
+				adminWrite.POST("/users/:id/impersonate", middleware.SessionOnly(), middleware.RequirePermission(entity.PermissionUsersImpersonate), handler.AuthHandler.Impersonate)
+			}
+
+			// Kept for existing clients; same as GET /admin/users
+			protected.GET("/users", middleware.RequirePermission(entity.PermissionUsersRead), handler.UserHandler.GetAllUsers)
+
+			// Role management
+			roles := protected.Group("/admin/roles")
+			roles.Use(middleware.NoImpersonation(), middleware.RequirePermission(entity.PermissionRolesManage))
+			{
+				roles.GET("", handler.RoleHandler.GetAllRoles)
+				roles.PUT("/:name", handler.RoleHandler.Save)
+				roles.DELETE("/:name", handler.RoleHandler.Delete)
 			}
 		}
 	}
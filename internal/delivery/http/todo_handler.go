@@ -1,8 +1,11 @@
 package http
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/delivery/http/middleware"
@@ -36,7 +39,7 @@ func (h *TodoHandler) Create(c *gin.Context) {
 
 	todo, err := h.todoUseCase.Create(userID, &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -53,7 +56,13 @@ func (h *TodoHandler) GetUserTodos(c *gin.Context) {
 		return
 	}
 
-	todos, err := h.todoUseCase.GetByUserID(userID)
+	filter, err := parseTodoFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	todos, err := h.todoUseCase.GetByUserID(userID, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -65,10 +74,15 @@ func (h *TodoHandler) GetUserTodos(c *gin.Context) {
 }
 
 func (h *TodoHandler) GetAllTodos(c *gin.Context) {
-	// This handler is only accessible by admins (enforced by middleware)
-	todos, err := h.todoUseCase.GetAll()
+	actor, exists := middleware.GetActor(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	todos, err := h.todoUseCase.GetAll(actor)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -77,6 +91,57 @@ func (h *TodoHandler) GetAllTodos(c *gin.Context) {
 	})
 }
 
+// Search finds the user's todos matching ?q=, best match first. The query
+// uses web search syntax: quoted phrases, "or" and a leading "-" to exclude.
+func (h *TodoHandler) Search(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	results, err := h.todoUseCase.Search(userID, c.Query("q"), limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+	})
+}
+
+// SearchAll is Search across every user's todos.
+func (h *TodoHandler) SearchAll(c *gin.Context) {
+	actor, exists := middleware.GetActor(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	results, err := h.todoUseCase.SearchAll(actor, c.Query("q"), limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+	})
+}
+
 func (h *TodoHandler) GetByID(c *gin.Context) {
 	todoID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -84,20 +149,19 @@ func (h *TodoHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	userID, exists := middleware.GetUserID(c)
+	actor, exists := middleware.GetActor(c)
 	if !exists {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
 		return
 	}
 
-	isAdmin := middleware.IsAdmin(c)
-
-	todo, err := h.todoUseCase.GetByID(todoID, userID, isAdmin)
+	todo, err := h.todoUseCase.GetByID(todoID, actor)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
+	c.Header("ETag", todoETag(todo.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"todo": todo,
 	})
@@ -110,7 +174,7 @@ func (h *TodoHandler) Update(c *gin.Context) {
 		return
 	}
 
-	userID, exists := middleware.GetUserID(c)
+	actor, exists := middleware.GetActor(c)
 	if !exists {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
 		return
@@ -121,15 +185,21 @@ func (h *TodoHandler) Update(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.Force = c.Query("force") == "true"
+	req.IfMatch, err = parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	isAdmin := middleware.IsAdmin(c)
-
-	todo, err := h.todoUseCase.Update(todoID, userID, &req, isAdmin)
+	todo, err := h.todoUseCase.Update(todoID, actor, &req)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
+	c.Header("ETag", todoETag(todo.Version))
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Todo updated successfully",
 		"todo":    todo,
@@ -143,22 +213,69 @@ func (h *TodoHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	actor, exists := middleware.GetActor(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.todoUseCase.Delete(todoID, actor, ifMatch)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Todo moved to trash",
+	})
+}
+
+func (h *TodoHandler) GetTrash(c *gin.Context) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
 		return
 	}
 
-	isAdmin := middleware.IsAdmin(c)
+	todos, err := h.todoUseCase.GetTrash(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"todos": todos,
+	})
+}
+
+func (h *TodoHandler) Restore(c *gin.Context) {
+	todoID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid todo ID"})
+		return
+	}
+
+	actor, exists := middleware.GetActor(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
 
-	err = h.todoUseCase.Delete(todoID, userID, isAdmin)
+	todo, err := h.todoUseCase.Restore(todoID, actor)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Todo deleted successfully",
+		"message": "Todo restored successfully",
+		"todo":    todo,
 	})
 }
 
@@ -169,7 +286,7 @@ func (h *TodoHandler) UpdateStatus(c *gin.Context) {
 		return
 	}
 
-	userID, exists := middleware.GetUserID(c)
+	actor, exists := middleware.GetActor(c)
 	if !exists {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
 		return
@@ -183,20 +300,265 @@ func (h *TodoHandler) UpdateStatus(c *gin.Context) {
 		return
 	}
 
-	updateReq := &model.UpdateTodoRequest{
-		Status: &req.Status,
+	ifMatch, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	isAdmin := middleware.IsAdmin(c)
+	updateReq := &model.UpdateTodoRequest{
+		Status:  &req.Status,
+		Force:   c.Query("force") == "true",
+		IfMatch: ifMatch,
+	}
 
-	todo, err := h.todoUseCase.Update(todoID, userID, updateReq, isAdmin)
+	todo, err := h.todoUseCase.Update(todoID, actor, updateReq)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
+	c.Header("ETag", todoETag(todo.Version))
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Todo status updated successfully",
 		"todo":    todo,
 	})
 }
+
+func (h *TodoHandler) AttachTag(c *gin.Context) {
+	todoID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid todo ID"})
+		return
+	}
+
+	actor, exists := middleware.GetActor(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var req model.AttachTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	todo, err := h.todoUseCase.AttachTag(todoID, actor, &req)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.Header("ETag", todoETag(todo.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tag attached successfully",
+		"todo":    todo,
+	})
+}
+
+func (h *TodoHandler) DetachTag(c *gin.Context) {
+	todoID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid todo ID"})
+		return
+	}
+
+	tagID, err := strconv.Atoi(c.Param("tagId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	actor, exists := middleware.GetActor(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	todo, err := h.todoUseCase.DetachTag(todoID, actor, tagID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.Header("ETag", todoETag(todo.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tag detached successfully",
+		"todo":    todo,
+	})
+}
+
+// Occurrences lists the next due dates of a recurring todo, ?count=10 by
+// default.
+func (h *TodoHandler) Occurrences(c *gin.Context) {
+	todoID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid todo ID"})
+		return
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", "10"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid count"})
+		return
+	}
+
+	actor, exists := middleware.GetActor(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	occurrences, err := h.todoUseCase.Occurrences(todoID, actor, count)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, occurrences)
+}
+
+// Bulk answers 200 when every operation succeeded and 207 Multi-Status
+// otherwise. Each result carries, as code, the status the single todo
+// endpoint would have answered with, along with its payload.
+func (h *TodoHandler) Bulk(c *gin.Context) {
+	actor, exists := middleware.GetActor(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	var req model.BulkTodoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.todoUseCase.Bulk(actor, &req)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	status := http.StatusOK
+	failed := 0
+	items := make([]gin.H, len(results))
+	for i, result := range results {
+		item := gin.H{"index": result.Index, "op": result.Op}
+		switch {
+		case errors.Is(result.Err, usecase.ErrRolledBack):
+			item["code"] = http.StatusFailedDependency
+			item["error"] = result.Err.Error()
+		case result.Err != nil:
+			code, body := errorResponse(http.StatusNotFound, result.Err)
+			for key, value := range body {
+				item[key] = value
+			}
+			item["code"] = code
+		case result.Op == model.BulkCreate:
+			item["code"] = http.StatusCreated
+			item["todo"] = result.Todo
+		default:
+			item["code"] = http.StatusOK
+			if result.Todo != nil {
+				item["todo"] = result.Todo
+			}
+		}
+		if result.Err != nil {
+			status = http.StatusMultiStatus
+			failed++
+		}
+		items[i] = item
+	}
+
+	c.JSON(status, gin.H{
+		"atomic":    req.Atomic,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+		"results":   items,
+	})
+}
+
+func (h *TodoHandler) GetHistory(c *gin.Context) {
+	todoID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid todo ID"})
+		return
+	}
+
+	actor, exists := middleware.GetActor(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID not found in context"})
+		return
+	}
+
+	revisions, err := h.todoUseCase.GetHistory(todoID, actor)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+	})
+}
+
+// todoETag is the entity tag of a todo at version, e.g. "3".
+func todoETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// parseIfMatch reads an If-Match header such as `"3"`, `"2", "3"` or `*`. It
+// returns nil without the header. Weak tags never match, as If-Match compares
+// strongly.
+func parseIfMatch(c *gin.Context) (*model.IfMatch, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return nil, nil
+	}
+	if header == "*" {
+		return &model.IfMatch{Any: true}, nil
+	}
+
+	ifMatch := &model.IfMatch{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		value, err := strconv.Unquote(tag)
+		if err != nil || !strings.HasPrefix(tag, `"`) {
+			return nil, fmt.Errorf("invalid If-Match header")
+		}
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			// Not one of our tags, so it cannot match
+			continue
+		}
+		ifMatch.Versions = append(ifMatch.Versions, version)
+	}
+
+	return ifMatch, nil
+}
+
+// parseTodoFilter reads ?tags=work,home&match=any|all.
+func parseTodoFilter(c *gin.Context) (*model.TodoFilter, error) {
+	filter := &model.TodoFilter{}
+	for _, name := range strings.Split(c.Query("tags"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			filter.Tags = append(filter.Tags, name)
+		}
+	}
+
+	switch c.DefaultQuery("match", "any") {
+	case "any":
+	case "all":
+		filter.MatchAll = true
+	default:
+		return nil, fmt.Errorf("match must be any or all")
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		header  string
+		want    *model.IfMatch
+		wantErr bool
+	}{
+		{"absent", "", nil, false},
+		{"blank", "   ", nil, false},
+		{"any", "*", &model.IfMatch{Any: true}, false},
+		{"single tag", `"3"`, &model.IfMatch{Versions: []int{3}}, false},
+		{"several tags", `"2", "3"`, &model.IfMatch{Versions: []int{2, 3}}, false},
+		{"surrounding spaces", `  "3"  `, &model.IfMatch{Versions: []int{3}}, false},
+		{"weak tags never match", `W/"3"`, &model.IfMatch{}, false},
+		{"weak and strong tags", `W/"2", "3"`, &model.IfMatch{Versions: []int{3}}, false},
+		{"foreign tag never matches", `"abc"`, &model.IfMatch{}, false},
+		{"unquoted", `3`, nil, true},
+		{"unterminated quote", `"3`, nil, true},
+		{"backquoted", "`3`", nil, true},
+		{"empty list item", `"3",`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("PUT", "/api/v1/todos/1", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-Match", tt.header)
+			}
+
+			got, err := parseIfMatch(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseIfMatch(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIfMatch(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
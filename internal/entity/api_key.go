@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// APIKey is a long-lived credential for scripts. Only the hash of the secret
+// is stored; Prefix identifies the key in listings and lookups. Scopes caps
+// the owner's permissions for requests made with the key.
+type APIKey struct {
+	ID         int          `json:"id"`
+	UserID     int          `json:"user_id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	KeyHash    string       `json:"-"`
+	Scopes     []Permission `json:"scopes"`
+	ExpiresAt  *time.Time   `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time   `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time   `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// IsActive reports whether the key may be used at now.
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// ScopeSet returns the key's scopes as a permission set.
+func (k *APIKey) ScopeSet() PermissionSet {
+	return NewPermissionSet(k.Scopes...)
+}
@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// Audit actions
+const (
+	AuditActionImpersonationStart   = "impersonation.start"
+	AuditActionImpersonationRequest = "impersonation.request"
+)
+
+// AuditLog records a privileged action. TargetUserID is 0 when the action is
+// not about a particular user.
+type AuditLog struct {
+	ID           int       `json:"id"`
+	ActorUserID  int       `json:"actor_user_id"`
+	Action       string    `json:"action"`
+	TargetUserID int       `json:"target_user_id,omitempty"`
+	Details      string    `json:"details,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
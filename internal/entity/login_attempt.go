@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// LoginAttempt tracks consecutive failed logins for a throttling key such as
+// a username or client IP.
+type LoginAttempt struct {
+	Key           string    `json:"key"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+	LockedUntil   time.Time `json:"locked_until"`
+}
+
+// IsLocked reports whether further attempts must be refused at now.
+func (a *LoginAttempt) IsLocked(now time.Time) bool {
+	return a != nil && now.Before(a.LockedUntil)
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// UserMFA holds a user's TOTP enrollment. The secret is usable for login only
+// once Enabled is set, which happens after the user confirms a first code.
+type UserMFA struct {
+	UserID       int        `json:"user_id"`
+	Secret       string     `json:"-"`
+	Enabled      bool       `json:"enabled"`
+	LastUsedStep int64      `json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
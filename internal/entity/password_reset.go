@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// PasswordResetToken is a single-use credential for resetting a password.
+// Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,119 @@
+package entity
+
+import "sort"
+
+// Permission is a single capability in "resource:action[:scope]" form. The
+// "own" scope covers the caller's own records, "any" covers everyone's.
+type Permission string
+
+const (
+	PermissionProfileRead   Permission = "profile:read"
+	PermissionTodosReadOwn  Permission = "todos:read:own"
+	PermissionTodosReadAny  Permission = "todos:read:any"
+	PermissionTodosWriteOwn Permission = "todos:write:own"
+	PermissionTodosWriteAny Permission = "todos:write:any"
+	PermissionUsersRead     Permission = "users:read"
+	PermissionUsersManage   Permission = "users:manage"
+	PermissionRolesManage   Permission = "roles:manage"
+	// PermissionUsersImpersonate allows minting tokens that act as another user
+	PermissionUsersImpersonate Permission = "users:impersonate"
+	PermissionAuditRead        Permission = "audit:read"
+)
+
+// Permissions lists every permission known to the application.
+var Permissions = []Permission{
+	PermissionProfileRead,
+	PermissionTodosReadOwn,
+	PermissionTodosReadAny,
+	PermissionTodosWriteOwn,
+	PermissionTodosWriteAny,
+	PermissionUsersRead,
+	PermissionUsersManage,
+	PermissionRolesManage,
+	PermissionUsersImpersonate,
+	PermissionAuditRead,
+}
+
+// IsKnownPermission reports whether p is one of Permissions.
+func IsKnownPermission(p Permission) bool {
+	for _, known := range Permissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionSet is an unordered set of permissions.
+type PermissionSet map[Permission]struct{}
+
+func NewPermissionSet(permissions ...Permission) PermissionSet {
+	set := make(PermissionSet, len(permissions))
+	for _, p := range permissions {
+		set[p] = struct{}{}
+	}
+	return set
+}
+
+func (s PermissionSet) Has(p Permission) bool {
+	_, ok := s[p]
+	return ok
+}
+
+// HasAny reports whether the set contains at least one of permissions.
+func (s PermissionSet) HasAny(permissions ...Permission) bool {
+	for _, p := range permissions {
+		if s.Has(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersect returns the permissions present in both sets.
+func (s PermissionSet) Intersect(other PermissionSet) PermissionSet {
+	result := make(PermissionSet)
+	for p := range s {
+		if other.Has(p) {
+			result[p] = struct{}{}
+		}
+	}
+	return result
+}
+
+// List returns the permissions sorted by name.
+func (s PermissionSet) List() []Permission {
+	list := make([]Permission, 0, len(s))
+	for p := range s {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// RoleDefinition is a named set of permissions stored in the database.
+type RoleDefinition struct {
+	Name        Role         `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// Actor is the authenticated caller of a use case together with the
+// permissions effective for this request.
+type Actor struct {
+	UserID      int
+	Permissions PermissionSet
+	// ImpersonatorID is the admin acting as UserID, or 0 for the user themselves
+	ImpersonatorID int
+}
+
+// Can reports whether the actor holds permission p.
+func (a *Actor) Can(p Permission) bool {
+	return a != nil && a.Permissions.Has(p)
+}
+
+// IsImpersonated reports whether the request is made by an admin acting as
+// the user.
+func (a *Actor) IsImpersonated() bool {
+	return a != nil && a.ImpersonatorID != 0
+}
@@ -0,0 +1,33 @@
+package entity
+
+// Progress counts a group of todos, such as a project or the subtasks of a
+// todo. Cancelled todos are left out; Done counts todos that were completed,
+// including archived ones.
+type Progress struct {
+	Total   int `json:"total"`
+	Done    int `json:"done"`
+	Percent int `json:"percent"`
+}
+
+// NewProgress computes Percent, rounding down.
+func NewProgress(total, done int) Progress {
+	progress := Progress{Total: total, Done: done}
+	if total > 0 {
+		progress.Percent = done * 100 / total
+	}
+	return progress
+}
+
+// SummarizeProgress counts todos the same way project progress is counted.
+func SummarizeProgress(todos []*Todo) Progress {
+	total, done := 0, 0
+	for _, todo := range todos {
+		if todo.CompletedAt != nil {
+			total++
+			done++
+		} else if todo.Status != TodoCancelled && todo.Status != TodoArchived {
+			total++
+		}
+	}
+	return NewProgress(total, done)
+}
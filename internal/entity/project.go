@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// Project groups a user's todos. Projects are listed by Position, then by
+// creation.
+type Project struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color,omitempty"`
+	Archived  bool      `json:"archived"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Progress counts the project's todos
+	Progress Progress `json:"progress"`
+}
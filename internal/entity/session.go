@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// Session is one login on one device. Every token issued for the login
+// carries the session ID, so revoking the session revokes them all.
+type Session struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// Current marks the session of the request that listed the sessions
+	Current bool `json:"current"`
+}
+
+// IsActive reports whether tokens of the session may be used at now.
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// Tag is a label a user attaches to their own todos. Names are unique per
+// user, compared case-insensitively.
+type Tag struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -5,16 +5,116 @@ import "time"
 type TodoStatus string
 
 const (
-	TodoPending   TodoStatus = "pending"
-	TodoCompleted TodoStatus = "completed"
+	TodoPending    TodoStatus = "pending"
+	TodoInProgress TodoStatus = "in_progress"
+	TodoBlocked    TodoStatus = "blocked"
+	TodoCompleted  TodoStatus = "completed"
+	TodoCancelled  TodoStatus = "cancelled"
+	TodoArchived   TodoStatus = "archived"
 )
 
+// todoTransitions lists the statuses each status may move to. Archived is
+// final.
+var todoTransitions = map[TodoStatus][]TodoStatus{
+	TodoPending:    {TodoInProgress, TodoBlocked, TodoCompleted, TodoCancelled},
+	TodoInProgress: {TodoPending, TodoBlocked, TodoCompleted, TodoCancelled},
+	TodoBlocked:    {TodoPending, TodoInProgress, TodoCancelled},
+	TodoCompleted:  {TodoPending, TodoInProgress, TodoArchived},
+	TodoCancelled:  {TodoPending, TodoArchived},
+	TodoArchived:   {},
+}
+
+// TodoStatuses returns every known status.
+func TodoStatuses() []TodoStatus {
+	return []TodoStatus{TodoPending, TodoInProgress, TodoBlocked, TodoCompleted, TodoCancelled, TodoArchived}
+}
+
+// IsOpen reports whether work on a todo with status s is still outstanding.
+func (s TodoStatus) IsOpen() bool {
+	return s == TodoPending || s == TodoInProgress || s == TodoBlocked
+}
+
+func (s TodoStatus) IsValid() bool {
+	_, ok := todoTransitions[s]
+	return ok
+}
+
+// NextStatuses returns the statuses s may move to.
+func (s TodoStatus) NextStatuses() []TodoStatus {
+	return append([]TodoStatus{}, todoTransitions[s]...)
+}
+
+// CanTransitionTo reports whether s may move to next. Keeping the current
+// status is always allowed.
+func (s TodoStatus) CanTransitionTo(next TodoStatus) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range todoTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 type Todo struct {
-	ID          int        `json:"id"`
-	UserID      int        `json:"user_id"`
+	ID        int  `json:"id"`
+	UserID    int  `json:"user_id"`
+	ProjectID *int `json:"project_id,omitempty"`
+	// ParentID is set for subtasks, which are ordered by Position
+	ParentID    *int       `json:"parent_id,omitempty"`
+	Position    int        `json:"position"`
 	Title       string     `json:"title"`
 	Description string     `json:"description"`
 	Status      TodoStatus `json:"status"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	// Timezone is the IANA zone recurrences are calculated in
+	Timezone string `json:"timezone"`
+	// Recurrence is an RFC 5545 RRULE; Occurrence numbers the todos of a
+	// series, starting at 1
+	Recurrence string `json:"recurrence,omitempty"`
+	Occurrence int    `json:"occurrence,omitempty"`
+	// Version starts at 1 and grows with every change; it is the todo's ETag
+	Version int `json:"version"`
+	// DeletedAt is set while the todo is in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Tags      []Tag      `json:"tags"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+
+	// Subtasks and Progress are only loaded for a single todo
+	Subtasks []*Todo   `json:"subtasks,omitempty"`
+	Progress *Progress `json:"progress,omitempty"`
+	// NextOccurrence is set on the response that completes a recurring todo
+	NextOccurrence *Todo `json:"next_occurrence,omitempty"`
+}
+
+// SetStatus moves the todo to status, which the caller has checked with
+// CanTransitionTo. CompletedAt is set on completion, kept when archiving and
+// cleared when the todo is reopened or cancelled.
+func (t *Todo) SetStatus(status TodoStatus, now time.Time) {
+	if status == t.Status {
+		return
+	}
+
+	switch status {
+	case TodoCompleted:
+		t.CompletedAt = &now
+	case TodoArchived:
+	default:
+		t.CompletedAt = nil
+	}
+	t.Status = status
+}
+
+// TodoSearchResult is a todo matching a full-text search. The snippets are
+// fragments of the title and description, HTML-escaped, with matches wrapped
+// in <mark>.
+type TodoSearchResult struct {
+	Todo               *Todo   `json:"todo"`
+	Rank               float64 `json:"rank"`
+	TitleSnippet       string  `json:"title_snippet"`
+	DescriptionSnippet string  `json:"description_snippet"`
 }
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TodoRevisionAction string
+
+const (
+	TodoRevisionUpdate  TodoRevisionAction = "update"
+	TodoRevisionDelete  TodoRevisionAction = "delete"
+	TodoRevisionRestore TodoRevisionAction = "restore"
+)
+
+// TodoRevision records one change to a todo. ActorUserID made the change; it
+// is 0 once that account is deleted. AdminAction is set when someone other
+// than the owner made it, through todos:write:any or impersonation, in which
+// case ImpersonatorID names the admin.
+type TodoRevision struct {
+	ID             int                `json:"id"`
+	TodoID         int                `json:"todo_id"`
+	ActorUserID    int                `json:"actor_user_id"`
+	ActorUsername  string             `json:"actor_username,omitempty"`
+	ImpersonatorID int                `json:"impersonator_id,omitempty"`
+	AdminAction    bool               `json:"admin_action"`
+	Action         TodoRevisionAction `json:"action"`
+	Changes        []FieldChange      `json:"changes"`
+	CreatedAt      time.Time          `json:"created_at"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// DiffTodo lists the fields a client can change that differ between before
+// and after. Due dates are given in RFC 3339, a missing project or due date
+// as "", and tags as their sorted, comma-separated names.
+func DiffTodo(before, after *Todo) []FieldChange {
+	changes := []FieldChange{}
+	add := func(field, from, to string) {
+		if from != to {
+			changes = append(changes, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+
+	add("title", before.Title, after.Title)
+	add("description", before.Description, after.Description)
+	add("status", string(before.Status), string(after.Status))
+	add("project_id", formatOptionalID(before.ProjectID), formatOptionalID(after.ProjectID))
+	add("position", strconv.Itoa(before.Position), strconv.Itoa(after.Position))
+	add("due_at", formatOptionalTime(before.DueAt), formatOptionalTime(after.DueAt))
+	add("timezone", before.Timezone, after.Timezone)
+	add("recurrence", before.Recurrence, after.Recurrence)
+	add("tags", formatTags(before.Tags), formatTags(after.Tags))
+	return changes
+}
+
+func formatTags(tags []Tag) string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func formatOptionalID(id *int) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.Itoa(*id)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
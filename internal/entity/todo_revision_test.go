@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffTodo(t *testing.T) {
+	projectID := 3
+	dueAt := time.Date(2026, 1, 2, 9, 0, 0, 0, time.FixedZone("CET", 3600))
+	work := Tag{ID: 1, Name: "work"}
+	home := Tag{ID: 2, Name: "home"}
+
+	base := Todo{Title: "Buy milk", Status: TodoPending, Timezone: "UTC", Tags: []Tag{work}}
+
+	tests := []struct {
+		name   string
+		change func(*Todo)
+		want   []FieldChange
+	}{
+		{"nothing", func(todo *Todo) {}, []FieldChange{}},
+		{"title", func(todo *Todo) { todo.Title = "Buy bread" }, []FieldChange{{"title", "Buy milk", "Buy bread"}}},
+		{"status", func(todo *Todo) { todo.Status = TodoCompleted }, []FieldChange{{"status", "pending", "completed"}}},
+		{"project", func(todo *Todo) { todo.ProjectID = &projectID }, []FieldChange{{"project_id", "", "3"}}},
+		{"due date in UTC", func(todo *Todo) { todo.DueAt = &dueAt }, []FieldChange{{"due_at", "", "2026-01-02T08:00:00Z"}}},
+		{"attached tag", func(todo *Todo) { todo.Tags = []Tag{work, home} }, []FieldChange{{"tags", "work", "home, work"}}},
+		{"detached tag", func(todo *Todo) { todo.Tags = nil }, []FieldChange{{"tags", "work", ""}}},
+		{"tags in another order", func(todo *Todo) { todo.Tags = []Tag{work} }, []FieldChange{}},
+		{"several fields", func(todo *Todo) {
+			todo.Position = 2
+			todo.Recurrence = "FREQ=DAILY"
+		}, []FieldChange{{"position", "0", "2"}, {"recurrence", "", "FREQ=DAILY"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := base
+			after := base
+			tt.change(&after)
+			if got := DiffTodo(&before, &after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffTodo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
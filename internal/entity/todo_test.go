@@ -0,0 +1,136 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTodoStatusCanTransitionTo(t *testing.T) {
+	// Every pair of statuses, with the moves that are allowed
+	allowed := map[TodoStatus][]TodoStatus{
+		TodoPending:    {TodoPending, TodoInProgress, TodoBlocked, TodoCompleted, TodoCancelled},
+		TodoInProgress: {TodoPending, TodoInProgress, TodoBlocked, TodoCompleted, TodoCancelled},
+		TodoBlocked:    {TodoPending, TodoInProgress, TodoBlocked, TodoCancelled},
+		TodoCompleted:  {TodoPending, TodoInProgress, TodoCompleted, TodoArchived},
+		TodoCancelled:  {TodoPending, TodoCancelled, TodoArchived},
+		TodoArchived:   {TodoArchived},
+	}
+
+	for _, from := range TodoStatuses() {
+		for _, to := range TodoStatuses() {
+			want := false
+			for _, status := range allowed[from] {
+				if status == to {
+					want = true
+				}
+			}
+			if got := from.CanTransitionTo(to); got != want {
+				t.Errorf("%s.CanTransitionTo(%s) = %v, want %v", from, to, got, want)
+			}
+		}
+		if from.CanTransitionTo("done") {
+			t.Errorf("%s.CanTransitionTo(done) = true for an unknown status", from)
+		}
+	}
+}
+
+func TestTodoStatusNextStatuses(t *testing.T) {
+	tests := []struct {
+		status TodoStatus
+		want   []TodoStatus
+	}{
+		{TodoPending, []TodoStatus{TodoInProgress, TodoBlocked, TodoCompleted, TodoCancelled}},
+		{TodoInProgress, []TodoStatus{TodoPending, TodoBlocked, TodoCompleted, TodoCancelled}},
+		{TodoBlocked, []TodoStatus{TodoPending, TodoInProgress, TodoCancelled}},
+		{TodoCompleted, []TodoStatus{TodoPending, TodoInProgress, TodoArchived}},
+		{TodoCancelled, []TodoStatus{TodoPending, TodoArchived}},
+		{TodoArchived, []TodoStatus{}},
+		{"done", []TodoStatus{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			got := tt.status.NextStatuses()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NextStatuses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Callers get a copy they may change
+	next := TodoPending.NextStatuses()
+	next[0] = TodoArchived
+	if TodoPending.CanTransitionTo(TodoArchived) {
+		t.Error("changing the result of NextStatuses changed the transitions")
+	}
+}
+
+func TestTodoStatusIsOpenAndIsValid(t *testing.T) {
+	tests := []struct {
+		status    TodoStatus
+		wantOpen  bool
+		wantValid bool
+	}{
+		{TodoPending, true, true},
+		{TodoInProgress, true, true},
+		{TodoBlocked, true, true},
+		{TodoCompleted, false, true},
+		{TodoCancelled, false, true},
+		{TodoArchived, false, true},
+		{"", false, false},
+		{"done", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			if got := tt.status.IsOpen(); got != tt.wantOpen {
+				t.Errorf("IsOpen() = %v, want %v", got, tt.wantOpen)
+			}
+			if got := tt.status.IsValid(); got != tt.wantValid {
+				t.Errorf("IsValid() = %v, want %v", got, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestTodoSetStatus(t *testing.T) {
+	earlier := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	now := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		from            TodoStatus
+		completedAt     *time.Time
+		to              TodoStatus
+		wantCompletedAt *time.Time
+	}{
+		{"complete", TodoInProgress, nil, TodoCompleted, &now},
+		{"start", TodoPending, nil, TodoInProgress, nil},
+		{"keep completed", TodoCompleted, &earlier, TodoCompleted, &earlier},
+		{"archive completed", TodoCompleted, &earlier, TodoArchived, &earlier},
+		{"archive cancelled", TodoCancelled, nil, TodoArchived, nil},
+		{"reopen", TodoCompleted, &earlier, TodoPending, nil},
+		{"resume", TodoCompleted, &earlier, TodoInProgress, nil},
+		{"cancel", TodoBlocked, nil, TodoCancelled, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo := &Todo{Status: tt.from, CompletedAt: tt.completedAt}
+			todo.SetStatus(tt.to, now)
+
+			if todo.Status != tt.to {
+				t.Errorf("Status = %s, want %s", todo.Status, tt.to)
+			}
+			switch {
+			case tt.wantCompletedAt == nil && todo.CompletedAt != nil:
+				t.Errorf("CompletedAt = %v, want nil", *todo.CompletedAt)
+			case tt.wantCompletedAt != nil && todo.CompletedAt == nil:
+				t.Errorf("CompletedAt = nil, want %v", *tt.wantCompletedAt)
+			case tt.wantCompletedAt != nil && !todo.CompletedAt.Equal(*tt.wantCompletedAt):
+				t.Errorf("CompletedAt = %v, want %v", *todo.CompletedAt, *tt.wantCompletedAt)
+			}
+		})
+	}
+}
@@ -4,16 +4,41 @@ import "time"
 
 type Role string
 
+// Built-in roles. Other roles can be defined at runtime.
 const (
-	UserRole  Role = "user"
-	AdminRole Role = "admin"
+	UserRole Role = "user"
+	// SupportRole can read every user's todos but not change anything
+	SupportRole Role = "support"
+	AdminRole   Role = "admin"
 )
 
+// IsBuiltIn reports whether r is one of the roles seeded by the migrations.
+func (r Role) IsBuiltIn() bool {
+	return r == UserRole || r == SupportRole || r == AdminRole
+}
+
 type User struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	Password  string    `json:"-"` // Don't include in JSON responses
-	Role      Role      `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Password string `json:"-"` // Don't include in JSON responses
+	Role     Role   `json:"role"`
+	// Email is optional; it is empty when the user has not set one
+	Email           string     `json:"email,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// TokenVersion is embedded in issued JWTs; bumping it revokes them all.
+	TokenVersion int `json:"-"`
+	// DisabledAt is set while an admin has blocked the account
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// HasVerifiedEmail reports whether mail may be sent to the user's address.
+func (u *User) HasVerifiedEmail() bool {
+	return u.Email != "" && u.EmailVerifiedAt != nil
+}
+
+// IsDisabled reports whether the account is blocked from signing in.
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
 }
@@ -0,0 +1,126 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// argon2MaxBytes bounds the work an overly long password can cause.
+	argon2MaxBytes = 1024
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+	argon2Prefix     = "$argon2id$"
+)
+
+// argon2idAlgorithm encodes hashes in the PHC string format:
+//
+//	$argon2id$v=19$m=<memory KiB>,t=<time>,p=<threads>$<salt>$<key>
+//
+// with salt and key in unpadded standard base64.
+type argon2idAlgorithm struct {
+	params argon2Params
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+func newArgon2id(time, memory, threads int) (*argon2idAlgorithm, error) {
+	if time < 1 || memory < 8*threads || threads < 1 || threads > 255 {
+		return nil, fmt.Errorf("invalid argon2id parameters: time=%d memory=%d threads=%d", time, memory, threads)
+	}
+	return &argon2idAlgorithm{
+		params: argon2Params{time: uint32(time), memory: uint32(memory), threads: uint8(threads)},
+	}, nil
+}
+
+func (a *argon2idAlgorithm) hash(password string) (string, error) {
+	return argon2idHash(password, a.params)
+}
+
+func (a *argon2idAlgorithm) hashLike(reference, password string) (string, error) {
+	p, _, _, err := decodeArgon2id(reference)
+	if err != nil {
+		p = a.params
+	}
+	return argon2idHash(password, p)
+}
+
+func argon2idHash(password string, p argon2Params) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, argon2KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix, argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a *argon2idAlgorithm) verify(hash, password string) (bool, error) {
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1, nil
+}
+
+func (a *argon2idAlgorithm) weakerThanConfigured(hash string) bool {
+	p, _, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return p.time < a.params.time ||
+		p.memory < a.params.memory ||
+		p.threads < a.params.threads ||
+		len(key) < argon2KeyLength
+}
+
+func (a *argon2idAlgorithm) recognises(hash string) bool {
+	return strings.HasPrefix(hash, argon2Prefix)
+}
+
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2id version")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, fmt.Errorf("invalid argon2id key")
+	}
+
+	return p, salt, key, nil
+}
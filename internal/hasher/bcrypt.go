@@ -0,0 +1,65 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptMaxBytes is the input length bcrypt actually hashes.
+const bcryptMaxBytes = 72
+
+// bcryptAlgorithm uses the modular crypt format bcrypt already produces,
+// e.g. "$2a$10$...", which carries the cost.
+type bcryptAlgorithm struct {
+	cost int
+}
+
+func newBcrypt(cost int) (*bcryptAlgorithm, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return &bcryptAlgorithm{cost: cost}, nil
+}
+
+func (a *bcryptAlgorithm) hash(password string) (string, error) {
+	return bcryptHash(password, a.cost)
+}
+
+func (a *bcryptAlgorithm) hashLike(reference, password string) (string, error) {
+	cost, err := bcrypt.Cost([]byte(reference))
+	if err != nil {
+		cost = a.cost
+	}
+	return bcryptHash(password, cost)
+}
+
+func bcryptHash(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func (a *bcryptAlgorithm) verify(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid bcrypt hash: %w", err)
+	}
+}
+
+func (a *bcryptAlgorithm) weakerThanConfigured(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < a.cost
+}
+
+func (a *bcryptAlgorithm) recognises(hash string) bool {
+	return hasPrefix(hash, "$2a$", "$2b$", "$2y$")
+}
@@ -0,0 +1,134 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/config"
+)
+
+// Algorithm names as used in PASSWORD_HASH_ALGORITHM.
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// ErrUnknownHash is returned for stored hashes no supported algorithm
+// recognises.
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Hasher hashes passwords with the configured algorithm and verifies
+// passwords against hashes of any supported algorithm. Hashes are encoded
+// with their algorithm and parameters, so settings can change over time.
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hash. The error is only set
+	// for hashes that cannot be parsed.
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash reports whether hash uses another algorithm or weaker
+	// parameters than new hashes get.
+	NeedsRehash(hash string) bool
+	// HashLike hashes password with the algorithm and parameters of
+	// reference, so verifying against the result costs as much as verifying
+	// against reference. Unrecognised references get the configured ones.
+	HashLike(reference, password string) (string, error)
+}
+
+// algorithm is a single hashing scheme.
+type algorithm interface {
+	hash(password string) (string, error)
+	// hashLike hashes with the parameters of reference, which this
+	// algorithm recognises.
+	hashLike(reference, password string) (string, error)
+	verify(hash, password string) (bool, error)
+	// weakerThanConfigured reports whether hash, which this algorithm
+	// recognises, uses weaker parameters than configured.
+	weakerThanConfigured(hash string) bool
+	recognises(hash string) bool
+}
+
+type hasher struct {
+	preferred  algorithm
+	algorithms []algorithm
+}
+
+// New returns a hasher producing hashes with the algorithm selected by
+// PASSWORD_HASH_ALGORITHM. Hashes of every supported algorithm still verify.
+func New(cfg config.PasswordHashConfig) (Hasher, error) {
+	bcryptAlg, err := newBcrypt(cfg.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+	argon2Alg, err := newArgon2id(cfg.Argon2Time, cfg.Argon2Memory, cfg.Argon2Threads)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &hasher{algorithms: []algorithm{bcryptAlg, argon2Alg}}
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt:
+		h.preferred = bcryptAlg
+	case AlgorithmArgon2id:
+		h.preferred = argon2Alg
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", cfg.Algorithm)
+	}
+
+	return h, nil
+}
+
+// MaxPasswordBytes is the longest password algorithm hashes in full.
+func MaxPasswordBytes(algorithm string) int {
+	if algorithm == AlgorithmBcrypt {
+		return bcryptMaxBytes
+	}
+	return argon2MaxBytes
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	return h.preferred.hash(password)
+}
+
+func (h *hasher) Verify(hash, password string) (bool, error) {
+	alg := h.find(hash)
+	if alg == nil {
+		return false, ErrUnknownHash
+	}
+	return alg.verify(hash, password)
+}
+
+func (h *hasher) NeedsRehash(hash string) bool {
+	alg := h.find(hash)
+	if alg != h.preferred {
+		return true
+	}
+	return alg.weakerThanConfigured(hash)
+}
+
+func (h *hasher) HashLike(reference, password string) (string, error) {
+	alg := h.find(reference)
+	if alg == nil {
+		return h.preferred.hash(password)
+	}
+	return alg.hashLike(reference, password)
+}
+
+func (h *hasher) find(hash string) algorithm {
+	for _, alg := range h.algorithms {
+		if alg.recognises(hash) {
+			return alg
+		}
+	}
+	return nil
+}
+
+// hasPrefix reports whether hash starts with any of prefixes.
+func hasPrefix(hash string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
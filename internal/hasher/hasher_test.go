@@ -0,0 +1,240 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/config"
+)
+
+const password = "correct horse battery staple"
+
+func testConfig(algorithm string) config.PasswordHashConfig {
+	return config.PasswordHashConfig{
+		Algorithm:     algorithm,
+		BcryptCost:    5,
+		Argon2Time:    1,
+		Argon2Memory:  64,
+		Argon2Threads: 1,
+	}
+}
+
+func mustNew(t *testing.T, cfg config.PasswordHashConfig) Hasher {
+	t.Helper()
+	h, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func mustHash(t *testing.T, h Hasher, password string) string {
+	t.Helper()
+	hash, err := h.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*config.PasswordHashConfig)
+		wantErr bool
+	}{
+		{"bcrypt", func(c *config.PasswordHashConfig) {}, false},
+		{"argon2id", func(c *config.PasswordHashConfig) { c.Algorithm = AlgorithmArgon2id }, false},
+		{"unknown algorithm", func(c *config.PasswordHashConfig) { c.Algorithm = "md5" }, true},
+		{"bcrypt cost too low", func(c *config.PasswordHashConfig) { c.BcryptCost = 3 }, true},
+		{"bcrypt cost too high", func(c *config.PasswordHashConfig) { c.BcryptCost = 32 }, true},
+		{"argon2 time zero", func(c *config.PasswordHashConfig) { c.Argon2Time = 0 }, true},
+		{"argon2 memory below 8 KiB per thread", func(c *config.PasswordHashConfig) { c.Argon2Threads = 4; c.Argon2Memory = 31 }, true},
+		{"argon2 threads zero", func(c *config.PasswordHashConfig) { c.Argon2Threads = 0 }, true},
+		{"argon2 threads above 255", func(c *config.PasswordHashConfig) { c.Argon2Threads = 256; c.Argon2Memory = 8 * 256 }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(AlgorithmBcrypt)
+			tt.modify(&cfg)
+			_, err := New(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	bcryptHasher := mustNew(t, testConfig(AlgorithmBcrypt))
+	argon2Hasher := mustNew(t, testConfig(AlgorithmArgon2id))
+	bcryptHash := mustHash(t, bcryptHasher, password)
+	argon2Hash := mustHash(t, argon2Hasher, password)
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+		wantErr  error
+	}{
+		{"bcrypt match", bcryptHash, password, true, nil},
+		{"bcrypt mismatch", bcryptHash, "wrong", false, nil},
+		{"argon2id match", argon2Hash, password, true, nil},
+		{"argon2id mismatch", argon2Hash, "wrong", false, nil},
+		{"argon2id empty password", argon2Hash, "", false, nil},
+		{"unknown format", "5f4dcc3b5aa765d61d8327deb882cf99", password, false, ErrUnknownHash},
+		{"empty hash", "", password, false, ErrUnknownHash},
+	}
+
+	// Both hashers verify hashes of either algorithm
+	for _, h := range []Hasher{bcryptHasher, argon2Hasher} {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := h.Verify(tt.hash, tt.password)
+				if got != tt.want {
+					t.Errorf("Verify() = %v, want %v", got, tt.want)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				if tt.wantErr == nil && err != nil {
+					t.Errorf("Verify() error = %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyMalformedHashes(t *testing.T) {
+	h := mustNew(t, testConfig(AlgorithmArgon2id))
+	valid := mustHash(t, h, password)
+	parts := strings.Split(valid, "$")
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"truncated bcrypt", "$2a$05$short"},
+		{"missing key", strings.Join(parts[:5], "$")},
+		{"wrong version", strings.Replace(valid, "v=19", "v=16", 1)},
+		{"bad parameters", strings.Replace(valid, parts[3], "m=x,t=1,p=1", 1)},
+		{"bad salt", strings.Replace(valid, parts[4], "!!!", 1)},
+		{"empty key", strings.Join(append(parts[:5:5], ""), "$")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := h.Verify(tt.hash, password)
+			if ok || err == nil {
+				t.Errorf("Verify(%q) = %v, %v, want an error", tt.hash, ok, err)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weakBcrypt := mustHash(t, mustNew(t, testConfig(AlgorithmBcrypt)), password)
+
+	strongBcryptConfig := testConfig(AlgorithmBcrypt)
+	strongBcryptConfig.BcryptCost = 6
+	strongBcrypt := mustHash(t, mustNew(t, strongBcryptConfig), password)
+
+	weakArgon2 := mustHash(t, mustNew(t, testConfig(AlgorithmArgon2id)), password)
+
+	strongArgon2Config := testConfig(AlgorithmArgon2id)
+	strongArgon2Config.Argon2Time = 2
+	strongArgon2 := mustHash(t, mustNew(t, strongArgon2Config), password)
+
+	moreMemoryConfig := testConfig(AlgorithmArgon2id)
+	moreMemoryConfig.Argon2Memory = 128
+	moreThreadsConfig := testConfig(AlgorithmArgon2id)
+	moreThreadsConfig.Argon2Threads = 2
+
+	tests := []struct {
+		name string
+		cfg  config.PasswordHashConfig
+		hash string
+		want bool
+	}{
+		{"bcrypt at configured cost", testConfig(AlgorithmBcrypt), weakBcrypt, false},
+		{"bcrypt above configured cost", testConfig(AlgorithmBcrypt), strongBcrypt, false},
+		{"bcrypt below configured cost", strongBcryptConfig, weakBcrypt, true},
+		{"bcrypt when argon2id is configured", testConfig(AlgorithmArgon2id), weakBcrypt, true},
+		{"argon2id at configured parameters", testConfig(AlgorithmArgon2id), weakArgon2, false},
+		{"argon2id above configured parameters", testConfig(AlgorithmArgon2id), strongArgon2, false},
+		{"argon2id with less time", strongArgon2Config, weakArgon2, true},
+		{"argon2id with less memory", moreMemoryConfig, weakArgon2, true},
+		{"argon2id with fewer threads", moreThreadsConfig, weakArgon2, true},
+		{"argon2id when bcrypt is configured", testConfig(AlgorithmBcrypt), weakArgon2, true},
+		{"malformed argon2id", testConfig(AlgorithmArgon2id), "$argon2id$broken", true},
+		{"unknown format", testConfig(AlgorithmBcrypt), "plaintext", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustNew(t, tt.cfg).NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashLike(t *testing.T) {
+	bcryptConfig := testConfig(AlgorithmBcrypt)
+	bcryptConfig.BcryptCost = 6
+	argon2Config := testConfig(AlgorithmArgon2id)
+	argon2Config.Argon2Time = 2
+	argon2Config.Argon2Memory = 128
+
+	bcryptReference := mustHash(t, mustNew(t, bcryptConfig), "other")
+	argon2Reference := mustHash(t, mustNew(t, argon2Config), "other")
+
+	tests := []struct {
+		name       string
+		cfg        config.PasswordHashConfig
+		reference  string
+		wantPrefix string
+	}{
+		{"bcrypt reference", testConfig(AlgorithmArgon2id), bcryptReference, "$2a$06$"},
+		{"argon2id reference", testConfig(AlgorithmBcrypt), argon2Reference, "$argon2id$v=19$m=128,t=2,p=1$"},
+		{"unknown reference uses configured bcrypt", testConfig(AlgorithmBcrypt), "", "$2a$05$"},
+		{"unknown reference uses configured argon2id", testConfig(AlgorithmArgon2id), "plaintext", "$argon2id$v=19$m=64,t=1,p=1$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := mustNew(t, tt.cfg)
+			hash, err := h.HashLike(tt.reference, password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(hash, tt.wantPrefix) {
+				t.Errorf("HashLike() = %q, want prefix %q", hash, tt.wantPrefix)
+			}
+			if ok, err := h.Verify(hash, password); !ok || err != nil {
+				t.Errorf("Verify(HashLike()) = %v, %v, want true", ok, err)
+			}
+		})
+	}
+}
+
+func TestHashUsesFreshSalt(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		h := mustNew(t, testConfig(algorithm))
+		if mustHash(t, h, password) == mustHash(t, h, password) {
+			t.Errorf("%s produced the same hash twice", algorithm)
+		}
+	}
+}
+
+func TestMaxPasswordBytes(t *testing.T) {
+	if got := MaxPasswordBytes(AlgorithmBcrypt); got != 72 {
+		t.Errorf("MaxPasswordBytes(bcrypt) = %d, want 72", got)
+	}
+	if got := MaxPasswordBytes(AlgorithmArgon2id); got != argon2MaxBytes {
+		t.Errorf("MaxPasswordBytes(argon2id) = %d, want %d", got, argon2MaxBytes)
+	}
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"expvar"
+	"io"
+	"net/http"
+)
+
+// counters is not published through expvar, so serving it does not also
+// expose the process command line and memory statistics.
+var counters = new(expvar.Map)
+
+// Counters served by Handler.
+var (
+	LoginFailures  = newCounter("login_failures_total")
+	LoginLockouts  = newCounter("login_lockouts_total")
+	LoginThrottled = newCounter("login_throttled_total")
+)
+
+func newCounter(name string) *expvar.Int {
+	counter := new(expvar.Int)
+	counters.Set(name, counter)
+	return counter
+}
+
+// Handler serves the counters as a JSON object.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		io.WriteString(w, counters.String())
+	})
+}
@@ -1,6 +1,8 @@
 package converter
 
 import (
+	"database/sql"
+
 	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
 )
@@ -9,58 +11,133 @@ func UserModelToEntity(m *model.UserModel) *entity.User {
 	if m == nil {
 		return nil
 	}
-	return &entity.User{
-		ID:        m.ID,
-		Username:  m.Username,
-		Password:  m.Password,
-		Role:      entity.Role(m.Role),
-		CreatedAt: m.CreatedAt,
-		UpdatedAt: m.UpdatedAt,
+	e := &entity.User{
+		ID:           m.ID,
+		Username:     m.Username,
+		Password:     m.Password,
+		Role:         entity.Role(m.Role),
+		Email:        m.Email.String,
+		TokenVersion: m.TokenVersion,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+	}
+	if m.EmailVerifiedAt.Valid {
+		emailVerifiedAt := m.EmailVerifiedAt.Time
+		e.EmailVerifiedAt = &emailVerifiedAt
 	}
+	if m.DisabledAt.Valid {
+		disabledAt := m.DisabledAt.Time
+		e.DisabledAt = &disabledAt
+	}
+	return e
 }
 
 func UserEntityToModel(e *entity.User) *model.UserModel {
 	if e == nil {
 		return nil
 	}
-	return &model.UserModel{
-		ID:        e.ID,
-		Username:  e.Username,
-		Password:  e.Password,
-		Role:      string(e.Role),
-		CreatedAt: e.CreatedAt,
-		UpdatedAt: e.UpdatedAt,
+	m := &model.UserModel{
+		ID:           e.ID,
+		Username:     e.Username,
+		Password:     e.Password,
+		Role:         string(e.Role),
+		Email:        sql.NullString{String: e.Email, Valid: e.Email != ""},
+		TokenVersion: e.TokenVersion,
+		CreatedAt:    e.CreatedAt,
+		UpdatedAt:    e.UpdatedAt,
+	}
+	if e.EmailVerifiedAt != nil {
+		m.EmailVerifiedAt = sql.NullTime{Time: *e.EmailVerifiedAt, Valid: true}
 	}
+	if e.DisabledAt != nil {
+		m.DisabledAt = sql.NullTime{Time: *e.DisabledAt, Valid: true}
+	}
+	return m
 }
 
 func TodoModelToEntity(m *model.TodoModel) *entity.Todo {
 	if m == nil {
 		return nil
 	}
-	return &entity.Todo{
+	e := &entity.Todo{
 		ID:          m.ID,
 		UserID:      m.UserID,
+		ProjectID:   nullIntPtr(m.ProjectID),
+		ParentID:    nullIntPtr(m.ParentID),
+		Position:    m.Position,
 		Title:       m.Title,
 		Description: m.Description,
 		Status:      entity.TodoStatus(m.Status),
+		Timezone:    m.Timezone,
+		Recurrence:  m.Recurrence,
+		Occurrence:  m.Occurrence,
+		Version:     m.Version,
+		Tags:        []entity.Tag{},
 		CreatedAt:   m.CreatedAt,
 		UpdatedAt:   m.UpdatedAt,
 	}
+	if m.CompletedAt.Valid {
+		completedAt := m.CompletedAt.Time
+		e.CompletedAt = &completedAt
+	}
+	if m.DueAt.Valid {
+		dueAt := m.DueAt.Time
+		e.DueAt = &dueAt
+	}
+	if m.DeletedAt.Valid {
+		deletedAt := m.DeletedAt.Time
+		e.DeletedAt = &deletedAt
+	}
+	return e
 }
 
 func TodoEntityToModel(e *entity.Todo) *model.TodoModel {
 	if e == nil {
 		return nil
 	}
-	return &model.TodoModel{
+	m := &model.TodoModel{
 		ID:          e.ID,
 		UserID:      e.UserID,
+		ProjectID:   intPtrNull(e.ProjectID),
+		ParentID:    intPtrNull(e.ParentID),
+		Position:    e.Position,
 		Title:       e.Title,
 		Description: e.Description,
 		Status:      string(e.Status),
+		Timezone:    e.Timezone,
+		Recurrence:  e.Recurrence,
+		Occurrence:  e.Occurrence,
+		Version:     e.Version,
 		CreatedAt:   e.CreatedAt,
 		UpdatedAt:   e.UpdatedAt,
 	}
+	if e.CompletedAt != nil {
+		m.CompletedAt = sql.NullTime{Time: *e.CompletedAt, Valid: true}
+	}
+	if e.DueAt != nil {
+		m.DueAt = sql.NullTime{Time: *e.DueAt, Valid: true}
+	}
+	if e.DeletedAt != nil {
+		m.DeletedAt = sql.NullTime{Time: *e.DeletedAt, Valid: true}
+	}
+	return m
+}
+
+// TodoRevisionModelToEntity leaves Changes to the caller, which decodes them.
+func TodoRevisionModelToEntity(m *model.TodoRevisionModel) *entity.TodoRevision {
+	if m == nil {
+		return nil
+	}
+	return &entity.TodoRevision{
+		ID:             m.ID,
+		TodoID:         m.TodoID,
+		ActorUserID:    int(m.ActorUserID.Int64),
+		ActorUsername:  m.ActorUsername,
+		ImpersonatorID: int(m.ImpersonatorID.Int64),
+		AdminAction:    m.AdminAction,
+		Action:         entity.TodoRevisionAction(m.Action),
+		CreatedAt:      m.CreatedAt,
+	}
 }
 
 func TodoModelsToEntities(models []*model.TodoModel) []*entity.Todo {
@@ -78,3 +155,228 @@ func UserModelsToEntities(models []*model.UserModel) []*entity.User {
 	}
 	return entities
 }
+
+func TagModelToEntity(m *model.TagModel) *entity.Tag {
+	if m == nil {
+		return nil
+	}
+	return &entity.Tag{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		Name:      m.Name,
+		Color:     m.Color,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+func TagModelsToEntities(models []*model.TagModel) []*entity.Tag {
+	entities := make([]*entity.Tag, len(models))
+	for i, m := range models {
+		entities[i] = TagModelToEntity(m)
+	}
+	return entities
+}
+
+func PasswordResetTokenModelToEntity(m *model.PasswordResetTokenModel) *entity.PasswordResetToken {
+	if m == nil {
+		return nil
+	}
+	e := &entity.PasswordResetToken{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		TokenHash: m.TokenHash,
+		ExpiresAt: m.ExpiresAt,
+		CreatedAt: m.CreatedAt,
+	}
+	if m.UsedAt.Valid {
+		usedAt := m.UsedAt.Time
+		e.UsedAt = &usedAt
+	}
+	return e
+}
+
+func LoginAttemptModelToEntity(m *model.LoginAttemptModel) *entity.LoginAttempt {
+	if m == nil {
+		return nil
+	}
+	e := &entity.LoginAttempt{
+		Key:           m.Key,
+		Failures:      m.Failures,
+		LastFailureAt: m.LastFailureAt,
+	}
+	if m.LockedUntil.Valid {
+		e.LockedUntil = m.LockedUntil.Time
+	}
+	return e
+}
+
+func UserMFAModelToEntity(m *model.UserMFAModel) *entity.UserMFA {
+	if m == nil {
+		return nil
+	}
+	e := &entity.UserMFA{
+		UserID:       m.UserID,
+		Secret:       m.Secret,
+		Enabled:      m.Enabled,
+		LastUsedStep: m.LastUsedStep,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+	}
+	if m.ConfirmedAt.Valid {
+		confirmedAt := m.ConfirmedAt.Time
+		e.ConfirmedAt = &confirmedAt
+	}
+	return e
+}
+
+func APIKeyModelToEntity(m *model.APIKeyModel) *entity.APIKey {
+	if m == nil {
+		return nil
+	}
+	e := &entity.APIKey{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		Name:      m.Name,
+		Prefix:    m.Prefix,
+		KeyHash:   m.KeyHash,
+		Scopes:    make([]entity.Permission, len(m.Scopes)),
+		CreatedAt: m.CreatedAt,
+	}
+	for i, scope := range m.Scopes {
+		e.Scopes[i] = entity.Permission(scope)
+	}
+	if m.ExpiresAt.Valid {
+		expiresAt := m.ExpiresAt.Time
+		e.ExpiresAt = &expiresAt
+	}
+	if m.LastUsedAt.Valid {
+		lastUsedAt := m.LastUsedAt.Time
+		e.LastUsedAt = &lastUsedAt
+	}
+	if m.RevokedAt.Valid {
+		revokedAt := m.RevokedAt.Time
+		e.RevokedAt = &revokedAt
+	}
+	return e
+}
+
+func APIKeyModelsToEntities(models []*model.APIKeyModel) []*entity.APIKey {
+	entities := make([]*entity.APIKey, len(models))
+	for i, m := range models {
+		entities[i] = APIKeyModelToEntity(m)
+	}
+	return entities
+}
+
+func RoleModelToEntity(m *model.RoleModel) *entity.RoleDefinition {
+	if m == nil {
+		return nil
+	}
+	e := &entity.RoleDefinition{
+		Name:        entity.Role(m.Name),
+		Description: m.Description,
+		Permissions: make([]entity.Permission, len(m.Permissions)),
+	}
+	for i, permission := range m.Permissions {
+		e.Permissions[i] = entity.Permission(permission)
+	}
+	return e
+}
+
+func RoleModelsToEntities(models []*model.RoleModel) []*entity.RoleDefinition {
+	entities := make([]*entity.RoleDefinition, len(models))
+	for i, m := range models {
+		entities[i] = RoleModelToEntity(m)
+	}
+	return entities
+}
+
+func AuditLogModelToEntity(m *model.AuditLogModel) *entity.AuditLog {
+	if m == nil {
+		return nil
+	}
+	return &entity.AuditLog{
+		ID:           m.ID,
+		ActorUserID:  m.ActorUserID,
+		Action:       m.Action,
+		TargetUserID: int(m.TargetUserID.Int64),
+		Details:      m.Details,
+		CreatedAt:    m.CreatedAt,
+	}
+}
+
+func AuditLogModelsToEntities(models []*model.AuditLogModel) []*entity.AuditLog {
+	entities := make([]*entity.AuditLog, len(models))
+	for i, m := range models {
+		entities[i] = AuditLogModelToEntity(m)
+	}
+	return entities
+}
+
+func SessionModelToEntity(m *model.SessionModel) *entity.Session {
+	if m == nil {
+		return nil
+	}
+	e := &entity.Session{
+		ID:         m.ID,
+		UserID:     m.UserID,
+		IPAddress:  m.IPAddress,
+		UserAgent:  m.UserAgent,
+		CreatedAt:  m.CreatedAt,
+		LastSeenAt: m.LastSeenAt,
+		ExpiresAt:  m.ExpiresAt,
+	}
+	if m.RevokedAt.Valid {
+		revokedAt := m.RevokedAt.Time
+		e.RevokedAt = &revokedAt
+	}
+	return e
+}
+
+func SessionModelsToEntities(models []*model.SessionModel) []*entity.Session {
+	entities := make([]*entity.Session, len(models))
+	for i, m := range models {
+		entities[i] = SessionModelToEntity(m)
+	}
+	return entities
+}
+
+func ProjectModelToEntity(m *model.ProjectModel) *entity.Project {
+	if m == nil {
+		return nil
+	}
+	return &entity.Project{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		Name:      m.Name,
+		Color:     m.Color,
+		Archived:  m.Archived,
+		Position:  m.Position,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+		Progress:  entity.NewProgress(m.TotalTodos, m.DoneTodos),
+	}
+}
+
+func ProjectModelsToEntities(models []*model.ProjectModel) []*entity.Project {
+	entities := make([]*entity.Project, len(models))
+	for i, m := range models {
+		entities[i] = ProjectModelToEntity(m)
+	}
+	return entities
+}
+
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	i := int(n.Int64)
+	return &i
+}
+
+func intPtrNull(i *int) sql.NullInt64 {
+	if i == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*i), Valid: true}
+}
@@ -1,57 +1,405 @@
 package model
 
 import (
+	"database/sql"
 	"database/sql/driver"
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
+
 	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
 )
 
 type UserModel struct {
+	ID              int            `db:"id"`
+	Username        string         `db:"username"`
+	Password        string         `db:"password"`
+	Role            string         `db:"role"`
+	Email           sql.NullString `db:"email"`
+	EmailVerifiedAt sql.NullTime   `db:"email_verified_at"`
+	TokenVersion    int            `db:"token_version"`
+	DisabledAt      sql.NullTime   `db:"disabled_at"`
+	CreatedAt       time.Time      `db:"created_at"`
+	UpdatedAt       time.Time      `db:"updated_at"`
+}
+
+type TodoModel struct {
+	ID          int           `db:"id"`
+	UserID      int           `db:"user_id"`
+	ProjectID   sql.NullInt64 `db:"project_id"`
+	ParentID    sql.NullInt64 `db:"parent_id"`
+	Position    int           `db:"position"`
+	Title       string        `db:"title"`
+	Description string        `db:"description"`
+	Status      string        `db:"status"`
+	CompletedAt sql.NullTime  `db:"completed_at"`
+	DueAt       sql.NullTime  `db:"due_at"`
+	Timezone    string        `db:"timezone"`
+	Recurrence  string        `db:"recurrence"`
+	Occurrence  int           `db:"occurrence"`
+	DeletedAt   sql.NullTime  `db:"deleted_at"`
+	Version     int           `db:"version"`
+	CreatedAt   time.Time     `db:"created_at"`
+	UpdatedAt   time.Time     `db:"updated_at"`
+}
+
+type TodoRevisionModel struct {
+	ID             int           `db:"id"`
+	TodoID         int           `db:"todo_id"`
+	ActorUserID    sql.NullInt64 `db:"actor_user_id"`
+	ActorUsername  string        `db:"actor_username"`
+	ImpersonatorID sql.NullInt64 `db:"impersonator_id"`
+	AdminAction    bool          `db:"admin_action"`
+	Action         string        `db:"action"`
+	// Changes is the JSON encoded list of field changes
+	Changes   []byte    `db:"changes"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type ProjectModel struct {
 	ID        int       `db:"id"`
-	Username  string    `db:"username"`
-	Password  string    `db:"password"`
-	Role      string    `db:"role"`
+	UserID    int       `db:"user_id"`
+	Name      string    `db:"name"`
+	Color     string    `db:"color"`
+	Archived  bool      `db:"archived"`
+	Position  int       `db:"position"`
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
+	// TotalTodos and DoneTodos are aggregated from todos
+	TotalTodos int `db:"total_todos"`
+	DoneTodos  int `db:"done_todos"`
 }
 
-type TodoModel struct {
-	ID          int       `db:"id"`
-	UserID      int       `db:"user_id"`
-	Title       string    `db:"title"`
-	Description string    `db:"description"`
-	Status      string    `db:"status"`
-	CreatedAt   time.Time `db:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at"`
+type TagModel struct {
+	ID        int       `db:"id"`
+	UserID    int       `db:"user_id"`
+	Name      string    `db:"name"`
+	Color     string    `db:"color"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type PasswordResetTokenModel struct {
+	ID        int          `db:"id"`
+	UserID    int          `db:"user_id"`
+	TokenHash string       `db:"token_hash"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	UsedAt    sql.NullTime `db:"used_at"`
+	CreatedAt time.Time    `db:"created_at"`
+}
+
+type LoginAttemptModel struct {
+	Key           string       `db:"key"`
+	Failures      int          `db:"failures"`
+	LastFailureAt time.Time    `db:"last_failure_at"`
+	LockedUntil   sql.NullTime `db:"locked_until"`
+}
+
+type UserMFAModel struct {
+	UserID       int          `db:"user_id"`
+	Secret       string       `db:"secret"`
+	Enabled      bool         `db:"enabled"`
+	LastUsedStep int64        `db:"last_used_step"`
+	ConfirmedAt  sql.NullTime `db:"confirmed_at"`
+	CreatedAt    time.Time    `db:"created_at"`
+	UpdatedAt    time.Time    `db:"updated_at"`
+}
+
+type SessionModel struct {
+	ID         int          `db:"id"`
+	UserID     int          `db:"user_id"`
+	IPAddress  string       `db:"ip_address"`
+	UserAgent  string       `db:"user_agent"`
+	CreatedAt  time.Time    `db:"created_at"`
+	LastSeenAt time.Time    `db:"last_seen_at"`
+	ExpiresAt  time.Time    `db:"expires_at"`
+	RevokedAt  sql.NullTime `db:"revoked_at"`
+}
+
+type AuditLogModel struct {
+	ID           int           `db:"id"`
+	ActorUserID  int           `db:"actor_user_id"`
+	Action       string        `db:"action"`
+	TargetUserID sql.NullInt64 `db:"target_user_id"`
+	Details      string        `db:"details"`
+	CreatedAt    time.Time     `db:"created_at"`
+}
+
+type APIKeyModel struct {
+	ID         int            `db:"id"`
+	UserID     int            `db:"user_id"`
+	Name       string         `db:"name"`
+	Prefix     string         `db:"prefix"`
+	KeyHash    string         `db:"key_hash"`
+	Scopes     pq.StringArray `db:"scopes"`
+	ExpiresAt  sql.NullTime   `db:"expires_at"`
+	LastUsedAt sql.NullTime   `db:"last_used_at"`
+	RevokedAt  sql.NullTime   `db:"revoked_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+type RoleModel struct {
+	Name        string         `db:"name"`
+	Description string         `db:"description"`
+	Permissions pq.StringArray `db:"permissions"`
 }
 
 // Register request/response models
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// Email is optional; a verification link is sent when it is given
+	Email string `json:"email"`
 }
 
 type LoginRequest struct {
+	// Username accepts either the username or the account's email address
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// ClientIP and UserAgent are filled in by the handler, never from the
+	// request body
+	ClientIP  string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
+// LoginResponse carries either a token or, when a second factor is needed,
+// an MFA challenge token to pass to the MFA login endpoints.
 type LoginResponse struct {
 	Token string      `json:"token"`
 	User  entity.User `json:"user"`
+
+	MFARequired           bool     `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool     `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string   `json:"mfa_token,omitempty"`
+	RecoveryCodes         []string `json:"recovery_codes,omitempty"`
+}
+
+// MFALoginRequest completes a login with either a TOTP code or a recovery code.
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	ClientIP     string `json:"-"`
+	UserAgent    string `json:"-"`
+}
+
+type MFAEnrollRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFAConfirmLoginRequest struct {
+	MFAToken  string `json:"mfa_token" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+	ClientIP  string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+type MFAEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	// Username accepts either the username or the account's email address
+	Username string `json:"username" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string              `json:"name" binding:"required"`
+	Scopes    []entity.Permission `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time          `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse is the only place the plain key is ever returned.
+type CreateAPIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey *entity.APIKey `json:"api_key"`
+}
+
+type SaveRoleRequest struct {
+	Description string              `json:"description"`
+	Permissions []entity.Permission `json:"permissions" binding:"required"`
+}
+
+type AssignRoleRequest struct {
+	Role entity.Role `json:"role" binding:"required"`
+}
+
+type ImpersonateRequest struct {
+	// Reason is stored in the audit log, e.g. a support ticket reference
+	Reason string `json:"reason" binding:"required"`
+}
+
+type ImpersonateResponse struct {
+	Token     string      `json:"token"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	User      entity.User `json:"user"`
+}
+
+// TokenExchangeRequest follows RFC 8693. Scope is a space-separated list of
+// permissions.
+type TokenExchangeRequest struct {
+	GrantType        string `json:"grant_type" binding:"required"`
+	SubjectToken     string `json:"subject_token" binding:"required"`
+	SubjectTokenType string `json:"subject_token_type" binding:"required"`
+	Audience         string `json:"audience" binding:"required"`
+	Scope            string `json:"scope" binding:"required"`
+}
+
+type TokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	Scope           string `json:"scope"`
 }
 
 type CreateTodoRequest struct {
 	Title       string `json:"title" binding:"required"`
 	Description string `json:"description"`
+	ProjectID   *int   `json:"project_id"`
+	// ParentID makes the todo a subtask; it joins the parent's project
+	ParentID *int       `json:"parent_id"`
+	DueAt    *time.Time `json:"due_at"`
+	// Timezone defaults to UTC
+	Timezone string `json:"timezone"`
+	// Recurrence is an RRULE such as "FREQ=WEEKLY;BYDAY=MO"; it needs DueAt
+	Recurrence string `json:"recurrence"`
 }
 
 type UpdateTodoRequest struct {
 	Title       *string            `json:"title"`
 	Description *string            `json:"description"`
 	Status      *entity.TodoStatus `json:"status"`
+	// ProjectID moves the todo; 0 takes it out of its project
+	ProjectID *int `json:"project_id"`
+	// Position reorders a subtask among its siblings
+	Position *int       `json:"position"`
+	DueAt    *time.Time `json:"due_at"`
+	Timezone *string    `json:"timezone"`
+	// Recurrence replaces the RRULE; an empty string stops the series
+	Recurrence *string `json:"recurrence"`
+	// Force completes a todo despite open subtasks; set from ?force=true
+	Force bool `json:"-"`
+	// IfMatch is the parsed If-Match header, nil when absent
+	IfMatch *IfMatch `json:"-"`
+}
+
+// IfMatch lists the todo versions an If-Match header accepts. Any is set
+// for "*", which accepts every existing todo.
+type IfMatch struct {
+	Any      bool
+	Versions []int
+}
+
+// Matches reports whether a todo at version satisfies the header.
+func (m *IfMatch) Matches(version int) bool {
+	if m.Any {
+		return true
+	}
+	for _, v := range m.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateProjectRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Color    string `json:"color"`
+	Position *int   `json:"position"`
+}
+
+type UpdateProjectRequest struct {
+	Name     *string `json:"name"`
+	Color    *string `json:"color"`
+	Position *int    `json:"position"`
+}
+
+type SaveTagRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Color string `json:"color"`
+}
+
+type AttachTagRequest struct {
+	TagID int `json:"tag_id" binding:"required"`
+}
+
+// Bulk todo operations
+const (
+	BulkCreate       = "create"
+	BulkUpdateStatus = "update_status"
+	BulkMove         = "move"
+	BulkDelete       = "delete"
+)
+
+// BulkTodoRequest applies several operations at once. With Atomic they run in
+// one transaction and the first failure undoes them all; otherwise each one
+// succeeds or fails on its own.
+type BulkTodoRequest struct {
+	Atomic     bool                `json:"atomic"`
+	Operations []BulkTodoOperation `json:"operations" binding:"required,dive"`
+}
+
+// BulkTodoOperation is one operation of a bulk request. Op decides which of
+// the other fields apply: Todo for create, Status and Force for
+// update_status, ProjectID (0 removes the project) for move. Version plays
+// the role of If-Match for every operation but create. Todo is bound with the
+// rules of a single create request.
+type BulkTodoOperation struct {
+	Op        string             `json:"op"`
+	ID        int                `json:"id"`
+	Version   *int               `json:"version"`
+	Todo      *CreateTodoRequest `json:"todo"`
+	Status    *entity.TodoStatus `json:"status"`
+	Force     bool               `json:"force"`
+	ProjectID *int               `json:"project_id"`
+}
+
+// BulkTodoResult reports the outcome of the operation at Index. Todo is the
+// created or changed todo; Err is nil on success.
+type BulkTodoResult struct {
+	Index int
+	Op    string
+	Todo  *entity.Todo
+	Err   error
+}
+
+type OccurrencesResponse struct {
+	Recurrence  string      `json:"recurrence"`
+	Timezone    string      `json:"timezone"`
+	Occurrences []time.Time `json:"occurrences"`
+}
+
+// TodoFilter narrows a todo listing. Tags are matched by name; with MatchAll
+// a todo needs every tag, otherwise any of them.
+type TodoFilter struct {
+	Tags     []string
+	MatchAll bool
 }
 
 // Custom type for role that implements sql driver interfaces
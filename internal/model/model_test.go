@@ -0,0 +1,25 @@
+package model
+
+import "testing"
+
+func TestIfMatchMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch IfMatch
+		version int
+		want    bool
+	}{
+		{"any", IfMatch{Any: true}, 7, true},
+		{"listed version", IfMatch{Versions: []int{2, 3}}, 3, true},
+		{"other version", IfMatch{Versions: []int{2, 3}}, 4, false},
+		{"no usable tags", IfMatch{}, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ifMatch.Matches(tt.version); got != tt.want {
+				t.Errorf("Matches(%d) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
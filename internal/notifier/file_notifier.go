@@ -0,0 +1,35 @@
+package notifier
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+)
+
+// fileNotifier writes each notification to its own file in dir, which makes
+// the messages easy to pick up from scripts during local development.
+type fileNotifier struct {
+	dir string
+}
+
+func NewFileNotifier(dir string) Notifier {
+	return &fileNotifier{dir: dir}
+}
+
+func (n *fileNotifier) SendPasswordReset(user *entity.User, token string) error {
+	if err := os.MkdirAll(n.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create notification directory: %w", err)
+	}
+
+	name := fmt.Sprintf("password-reset-%d-%d.txt", user.ID, time.Now().UnixNano())
+	body := fmt.Sprintf("To: %s\nSubject: Password reset\n\nUse this token to reset your password:\n\n%s\n", user.Username, token)
+
+	if err := os.WriteFile(filepath.Join(n.dir, name), []byte(body), 0o600); err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+
+	return nil
+}
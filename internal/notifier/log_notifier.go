@@ -0,0 +1,20 @@
+package notifier
+
+import (
+	"log"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+)
+
+// logNotifier writes notifications to the application log. It is meant for
+// local development only since it exposes reset tokens in the logs.
+type logNotifier struct{}
+
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+func (n *logNotifier) SendPasswordReset(user *entity.User, token string) error {
+	log.Printf("password reset requested for user %q (id %d): token=%s", user.Username, user.ID, token)
+	return nil
+}
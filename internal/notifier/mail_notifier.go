@@ -0,0 +1,38 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/mailer"
+)
+
+// mailNotifier emails notifications to the user's verified address. Users
+// without one are skipped, since an unverified address may belong to someone
+// else.
+type mailNotifier struct {
+	mailer mailer.Mailer
+}
+
+func NewMailNotifier(m mailer.Mailer) Notifier {
+	return &mailNotifier{mailer: m}
+}
+
+func (n *mailNotifier) SendPasswordReset(user *entity.User, token string) error {
+	if !user.HasVerifiedEmail() {
+		log.Printf("password reset for user %d not sent: no verified email", user.ID)
+		return nil
+	}
+
+	err := n.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body:    fmt.Sprintf("Hi %s,\n\nUse this token to reset your password:\n\n%s\n\nIf you did not ask for a reset, ignore this email.\n", user.Username, token),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to email password reset: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package notifier
+
+import (
+	"fmt"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/config"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/mailer"
+)
+
+// Notifier delivers out-of-band messages to users.
+type Notifier interface {
+	SendPasswordReset(user *entity.User, token string) error
+}
+
+// New returns the notifier selected by NOTIFIER_DRIVER. m is used by the
+// mail driver.
+func New(cfg config.NotifierConfig, m mailer.Mailer) (Notifier, error) {
+	switch cfg.Driver {
+	case "mail":
+		return NewMailNotifier(m), nil
+	case "log":
+		return NewLogNotifier(), nil
+	case "file":
+		return NewFileNotifier(cfg.FileDir), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier driver %q", cfg.Driver)
+	}
+}
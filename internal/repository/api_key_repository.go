@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model/converter"
+)
+
+type APIKeyRepository interface {
+	Create(key *entity.APIKey) (*entity.APIKey, error)
+	GetByUserID(userID int) ([]*entity.APIKey, error)
+	GetByPrefix(prefix string) (*entity.APIKey, error)
+	Revoke(id, userID int) error
+	RevokeAllByUserID(userID int) error
+	TouchLastUsed(id int, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(key *entity.APIKey) (*entity.APIKey, error) {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+	`
+
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+
+	var keyModel model.APIKeyModel
+	err := r.db.QueryRow(query, key.UserID, key.Name, key.Prefix, key.KeyHash, pq.Array(scopes), key.ExpiresAt, time.Now()).
+		Scan(&keyModel.ID, &keyModel.UserID, &keyModel.Name, &keyModel.Prefix, &keyModel.KeyHash, &keyModel.Scopes, &keyModel.ExpiresAt, &keyModel.LastUsedAt, &keyModel.RevokedAt, &keyModel.CreatedAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return converter.APIKeyModelToEntity(&keyModel), nil
+}
+
+func (r *apiKeyRepository) GetByUserID(userID int) ([]*entity.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api keys by user id: %w", err)
+	}
+	defer rows.Close()
+
+	var keyModels []*model.APIKeyModel
+	for rows.Next() {
+		var keyModel model.APIKeyModel
+		err := rows.Scan(&keyModel.ID, &keyModel.UserID, &keyModel.Name, &keyModel.Prefix, &keyModel.KeyHash, &keyModel.Scopes, &keyModel.ExpiresAt, &keyModel.LastUsedAt, &keyModel.RevokedAt, &keyModel.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keyModels = append(keyModels, &keyModel)
+	}
+
+	return converter.APIKeyModelsToEntities(keyModels), nil
+}
+
+func (r *apiKeyRepository) GetByPrefix(prefix string) (*entity.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE prefix = $1
+	`
+
+	var keyModel model.APIKeyModel
+	err := r.db.QueryRow(query, prefix).
+		Scan(&keyModel.ID, &keyModel.UserID, &keyModel.Name, &keyModel.Prefix, &keyModel.KeyHash, &keyModel.Scopes, &keyModel.ExpiresAt, &keyModel.LastUsedAt, &keyModel.RevokedAt, &keyModel.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key not found")
+		}
+		return nil, fmt.Errorf("failed to get api key by prefix: %w", err)
+	}
+
+	return converter.APIKeyModelToEntity(&keyModel), nil
+}
+
+func (r *apiKeyRepository) Revoke(id, userID int) error {
+	query := `UPDATE api_keys SET revoked_at = $3 WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	result, err := r.db.Exec(query, id, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("api key not found")
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) RevokeAllByUserID(userID int) error {
+	query := `UPDATE api_keys SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL`
+
+	if _, err := r.db.Exec(query, userID, time.Now()); err != nil {
+		return fmt.Errorf("failed to revoke api keys: %w", err)
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(id int, at time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`
+
+	if _, err := r.db.Exec(query, id, at); err != nil {
+		return fmt.Errorf("failed to update api key last used: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model/converter"
+)
+
+type AuditLogRepository interface {
+	Create(entry *entity.AuditLog) (*entity.AuditLog, error)
+	GetRecent(limit int) ([]*entity.AuditLog, error)
+}
+
+type auditLogRepository struct {
+	db *sql.DB
+}
+
+func NewAuditLogRepository(db *sql.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+func (r *auditLogRepository) Create(entry *entity.AuditLog) (*entity.AuditLog, error) {
+	query := `
+		INSERT INTO audit_logs (actor_user_id, action, target_user_id, details, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, actor_user_id, action, target_user_id, details, created_at
+	`
+
+	targetUserID := sql.NullInt64{Int64: int64(entry.TargetUserID), Valid: entry.TargetUserID != 0}
+
+	var logModel model.AuditLogModel
+	err := r.db.QueryRow(query, entry.ActorUserID, entry.Action, targetUserID, entry.Details, time.Now()).
+		Scan(&logModel.ID, &logModel.ActorUserID, &logModel.Action, &logModel.TargetUserID, &logModel.Details, &logModel.CreatedAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	return converter.AuditLogModelToEntity(&logModel), nil
+}
+
+func (r *auditLogRepository) GetRecent(limit int) ([]*entity.AuditLog, error) {
+	query := `
+		SELECT id, actor_user_id, action, target_user_id, details, created_at
+		FROM audit_logs
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1
+	`
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logModels []*model.AuditLogModel
+	for rows.Next() {
+		var logModel model.AuditLogModel
+		err := rows.Scan(&logModel.ID, &logModel.ActorUserID, &logModel.Action, &logModel.TargetUserID, &logModel.Details, &logModel.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit log: %w", err)
+		}
+		logModels = append(logModels, &logModel)
+	}
+
+	return converter.AuditLogModelsToEntities(logModels), nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// BreachedPasswordRepository answers k-anonymity range queries: callers send
+// only the first five hex characters of a password's SHA-1 hash and compare
+// the returned suffixes themselves.
+type BreachedPasswordRepository interface {
+	GetSuffixesByPrefix(prefix string) ([]string, error)
+}
+
+type fileBreachedPasswordRepository struct {
+	ranges map[string][]string
+}
+
+// NewFileBreachedPasswordRepository loads a list of uppercase or lowercase
+// SHA-1 hashes, one "HASH[:COUNT]" per line, grouped by 5-character prefix.
+func NewFileBreachedPasswordRepository(path string) (BreachedPasswordRepository, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	ranges := make(map[string][]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != 40 {
+			continue
+		}
+
+		ranges[hash[:5]] = append(ranges[hash[:5]], hash[5:])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return &fileBreachedPasswordRepository{ranges: ranges}, nil
+}
+
+func (r *fileBreachedPasswordRepository) GetSuffixesByPrefix(prefix string) ([]string, error) {
+	return r.ranges[strings.ToUpper(prefix)], nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+)
+
+// maxMemoryLoginAttempts bounds the process-local store so a flood of
+// distinct usernames or addresses cannot grow it without limit.
+const maxMemoryLoginAttempts = 100000
+
+type memoryLoginAttemptRepository struct {
+	mu         sync.Mutex
+	attempts   map[string]entity.LoginAttempt
+	maxEntries int
+	lastPrune  time.Time
+}
+
+// NewMemoryLoginAttemptRepository returns a process-local store. Counters are
+// not shared between replicas and are lost on restart.
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{
+		attempts:   make(map[string]entity.LoginAttempt),
+		maxEntries: maxMemoryLoginAttempts,
+	}
+}
+
+func (r *memoryLoginAttemptRepository) Get(key string) (*entity.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return &entity.LoginAttempt{Key: key}, nil
+	}
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepository) RecordFailure(key string, at time.Time, window time.Duration) (*entity.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if at.Sub(r.lastPrune) >= window {
+		r.prune(at, window)
+	}
+
+	attempt, ok := r.attempts[key]
+	if !ok && len(r.attempts) >= r.maxEntries {
+		r.prune(at, window)
+		if len(r.attempts) >= r.maxEntries {
+			r.evictOldest()
+		}
+	}
+	if !ok || attempt.LastFailureAt.Before(at.Add(-window)) {
+		attempt = entity.LoginAttempt{Key: key, LockedUntil: attempt.LockedUntil}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	r.attempts[key] = attempt
+
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepository) Lock(key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt := r.attempts[key]
+	attempt.Key = key
+	attempt.LockedUntil = until
+	r.attempts[key] = attempt
+
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Reset(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+// prune drops entries whose failures are older than window and whose lockout
+// has ended; they would start from zero on the next failure anyway.
+func (r *memoryLoginAttemptRepository) prune(at time.Time, window time.Duration) {
+	cutoff := at.Add(-window)
+	for key, attempt := range r.attempts {
+		if attempt.LastFailureAt.Before(cutoff) && !attempt.LockedUntil.After(at) {
+			delete(r.attempts, key)
+		}
+	}
+	r.lastPrune = at
+}
+
+// evictOldest makes room when the store is full of live entries, dropping
+// the one whose last failure is the oldest.
+func (r *memoryLoginAttemptRepository) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, attempt := range r.attempts {
+		if oldestKey == "" || attempt.LastFailureAt.Before(oldest) {
+			oldestKey, oldest = key, attempt.LastFailureAt
+		}
+	}
+	delete(r.attempts, oldestKey)
+}
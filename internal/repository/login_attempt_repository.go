@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model/converter"
+)
+
+// LoginAttemptRepository stores failed login counters. RecordFailure starts
+// a new count when the previous failure is older than window.
+type LoginAttemptRepository interface {
+	Get(key string) (*entity.LoginAttempt, error)
+	RecordFailure(key string, at time.Time, window time.Duration) (*entity.LoginAttempt, error)
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+}
+
+type loginAttemptRepository struct {
+	db *sql.DB
+}
+
+// NewLoginAttemptRepository returns a Postgres-backed store that is shared by
+// every replica of the application.
+func NewLoginAttemptRepository(db *sql.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+func (r *loginAttemptRepository) Get(key string) (*entity.LoginAttempt, error) {
+	query := `
+		SELECT key, failures, last_failure_at, locked_until
+		FROM login_attempts
+		WHERE key = $1
+	`
+
+	var attemptModel model.LoginAttemptModel
+	err := r.db.QueryRow(query, key).
+		Scan(&attemptModel.Key, &attemptModel.Failures, &attemptModel.LastFailureAt, &attemptModel.LockedUntil)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &entity.LoginAttempt{Key: key}, nil
+		}
+		return nil, fmt.Errorf("failed to get login attempt: %w", err)
+	}
+
+	return converter.LoginAttemptModelToEntity(&attemptModel), nil
+}
+
+func (r *loginAttemptRepository) RecordFailure(key string, at time.Time, window time.Duration) (*entity.LoginAttempt, error) {
+	// The increment happens in a single statement so concurrent failures on
+	// different replicas are all counted
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE
+				WHEN login_attempts.last_failure_at < $3 THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING key, failures, last_failure_at, locked_until
+	`
+
+	var attemptModel model.LoginAttemptModel
+	err := r.db.QueryRow(query, key, at, at.Add(-window)).
+		Scan(&attemptModel.Key, &attemptModel.Failures, &attemptModel.LastFailureAt, &attemptModel.LockedUntil)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	return converter.LoginAttemptModelToEntity(&attemptModel), nil
+}
+
+func (r *loginAttemptRepository) Lock(key string, until time.Time) error {
+	query := `UPDATE login_attempts SET locked_until = $2 WHERE key = $1`
+
+	if _, err := r.db.Exec(query, key, until); err != nil {
+		return fmt.Errorf("failed to lock login attempts: %w", err)
+	}
+
+	return nil
+}
+
+func (r *loginAttemptRepository) Reset(key string) error {
+	query := `DELETE FROM login_attempts WHERE key = $1`
+
+	if _, err := r.db.Exec(query, key); err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+
+	return nil
+}
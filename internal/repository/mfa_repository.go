@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model/converter"
+)
+
+type MFARepository interface {
+	GetByUserID(userID int) (*entity.UserMFA, error)
+	SavePending(userID int, secret string) (*entity.UserMFA, error)
+	Enable(userID int, step int64) error
+	MarkStepUsed(userID int, step int64) error
+	Delete(userID int) error
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	UseRecoveryCode(userID int, codeHash string) error
+}
+
+type mfaRepository struct {
+	db *sql.DB
+}
+
+func NewMFARepository(db *sql.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) GetByUserID(userID int) (*entity.UserMFA, error) {
+	query := `
+		SELECT user_id, secret, enabled, last_used_step, confirmed_at, created_at, updated_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`
+
+	var mfaModel model.UserMFAModel
+	err := r.db.QueryRow(query, userID).
+		Scan(&mfaModel.UserID, &mfaModel.Secret, &mfaModel.Enabled, &mfaModel.LastUsedStep, &mfaModel.ConfirmedAt, &mfaModel.CreatedAt, &mfaModel.UpdatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("mfa not configured")
+		}
+		return nil, fmt.Errorf("failed to get mfa by user id: %w", err)
+	}
+
+	return converter.UserMFAModelToEntity(&mfaModel), nil
+}
+
+// SavePending stores a new, not yet confirmed secret. An already enabled
+// enrollment is left untouched.
+func (r *mfaRepository) SavePending(userID int, secret string) (*entity.UserMFA, error) {
+	query := `
+		INSERT INTO user_mfa (user_id, secret, enabled, created_at, updated_at)
+		VALUES ($1, $2, FALSE, $3, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, updated_at = EXCLUDED.updated_at
+		WHERE user_mfa.enabled = FALSE
+		RETURNING user_id, secret, enabled, last_used_step, confirmed_at, created_at, updated_at
+	`
+
+	var mfaModel model.UserMFAModel
+	err := r.db.QueryRow(query, userID, secret, time.Now()).
+		Scan(&mfaModel.UserID, &mfaModel.Secret, &mfaModel.Enabled, &mfaModel.LastUsedStep, &mfaModel.ConfirmedAt, &mfaModel.CreatedAt, &mfaModel.UpdatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("mfa is already enabled")
+		}
+		return nil, fmt.Errorf("failed to save mfa secret: %w", err)
+	}
+
+	return converter.UserMFAModelToEntity(&mfaModel), nil
+}
+
+func (r *mfaRepository) Enable(userID int, step int64) error {
+	query := `
+		UPDATE user_mfa
+		SET enabled = TRUE, last_used_step = $2, confirmed_at = $3, updated_at = $3
+		WHERE user_id = $1 AND enabled = FALSE
+	`
+
+	result, err := r.db.Exec(query, userID, step, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("no pending mfa enrollment")
+	}
+
+	return nil
+}
+
+// MarkStepUsed records the time step of an accepted code. It fails when that
+// step or a later one was already used, so each code works only once.
+func (r *mfaRepository) MarkStepUsed(userID int, step int64) error {
+	query := `UPDATE user_mfa SET last_used_step = $2, updated_at = $3 WHERE user_id = $1 AND last_used_step < $2`
+
+	result, err := r.db.Exec(query, userID, step, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record mfa code use: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("mfa code already used")
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) Delete(userID int) error {
+	if _, err := r.db.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	if _, err := r.db.Exec(`DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete mfa: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	now := time.Now()
+	for _, codeHash := range codeHashes {
+		_, err := tx.Exec(`INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, $3)`, userID, codeHash, now)
+		if err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) UseRecoveryCode(userID int, codeHash string) error {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = $3
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, codeHash, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("invalid recovery code")
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model/converter"
+)
+
+type PasswordResetRepository interface {
+	Create(token *entity.PasswordResetToken) (*entity.PasswordResetToken, error)
+	GetByTokenHash(tokenHash string) (*entity.PasswordResetToken, error)
+	MarkUsed(id int) error
+	MarkAllUsedByUserID(userID int) error
+}
+
+type passwordResetRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordResetRepository(db *sql.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (r *passwordResetRepository) Create(token *entity.PasswordResetToken) (*entity.PasswordResetToken, error) {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, user_id, token_hash, expires_at, used_at, created_at
+	`
+
+	var tokenModel model.PasswordResetTokenModel
+	err := r.db.QueryRow(query, token.UserID, token.TokenHash, token.ExpiresAt, time.Now()).
+		Scan(&tokenModel.ID, &tokenModel.UserID, &tokenModel.TokenHash, &tokenModel.ExpiresAt, &tokenModel.UsedAt, &tokenModel.CreatedAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	return converter.PasswordResetTokenModelToEntity(&tokenModel), nil
+}
+
+func (r *passwordResetRepository) GetByTokenHash(tokenHash string) (*entity.PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1
+	`
+
+	var tokenModel model.PasswordResetTokenModel
+	err := r.db.QueryRow(query, tokenHash).
+		Scan(&tokenModel.ID, &tokenModel.UserID, &tokenModel.TokenHash, &tokenModel.ExpiresAt, &tokenModel.UsedAt, &tokenModel.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("password reset token not found")
+		}
+		return nil, fmt.Errorf("failed to get password reset token: %w", err)
+	}
+
+	return converter.PasswordResetTokenModelToEntity(&tokenModel), nil
+}
+
+// MarkUsed consumes the token. It fails if the token was already used, which
+// keeps tokens single-use even when two resets race.
+func (r *passwordResetRepository) MarkUsed(id int) error {
+	query := `UPDATE password_reset_tokens SET used_at = $2 WHERE id = $1 AND used_at IS NULL`
+
+	result, err := r.db.Exec(query, id, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to mark password reset token as used: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("password reset token already used")
+	}
+
+	return nil
+}
+
+// MarkAllUsedByUserID consumes every outstanding token of the user, so none
+// of them outlives a password change.
+func (r *passwordResetRepository) MarkAllUsedByUserID(userID int) error {
+	query := `UPDATE password_reset_tokens SET used_at = $2 WHERE user_id = $1 AND used_at IS NULL`
+
+	if _, err := r.db.Exec(query, userID, time.Now()); err != nil {
+		return fmt.Errorf("failed to mark password reset tokens as used: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model/converter"
+)
+
+type ProjectRepository interface {
+	Create(project *entity.Project) (*entity.Project, error)
+	GetByID(id int) (*entity.Project, error)
+	GetByUserID(userID int, includeArchived bool) ([]*entity.Project, error)
+	Update(project *entity.Project) (*entity.Project, error)
+	Delete(id int) error
+	CountOpenTodos(id int) (int, error)
+}
+
+// projectSelect loads projects with their progress. Cancelled todos, also
+// when archived later, do not count.
+const projectSelect = `
+	SELECT p.id, p.user_id, p.name, p.color, p.archived, p.position, p.created_at, p.updated_at,
+		COUNT(t.id) FILTER (WHERE t.status <> 'cancelled' AND (t.status <> 'archived' OR t.completed_at IS NOT NULL)),
+		COUNT(t.id) FILTER (WHERE t.completed_at IS NOT NULL)
+	FROM projects p
+	LEFT JOIN todos t ON t.project_id = p.id AND t.deleted_at IS NULL
+`
+
+type projectRepository struct {
+	db DBTX
+}
+
+func NewProjectRepository(db DBTX) ProjectRepository {
+	return &projectRepository{db: db}
+}
+
+func scanProject(row rowScanner) (*model.ProjectModel, error) {
+	var projectModel model.ProjectModel
+	err := row.Scan(&projectModel.ID, &projectModel.UserID, &projectModel.Name, &projectModel.Color, &projectModel.Archived, &projectModel.Position, &projectModel.CreatedAt, &projectModel.UpdatedAt, &projectModel.TotalTodos, &projectModel.DoneTodos)
+	if err != nil {
+		return nil, err
+	}
+	return &projectModel, nil
+}
+
+func (r *projectRepository) Create(project *entity.Project) (*entity.Project, error) {
+	query := `
+		INSERT INTO projects (user_id, name, color, position, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, user_id, name, color, archived, position, created_at, updated_at
+	`
+
+	now := time.Now()
+	var projectModel model.ProjectModel
+	err := r.db.QueryRow(query, project.UserID, project.Name, project.Color, project.Position, now, now).
+		Scan(&projectModel.ID, &projectModel.UserID, &projectModel.Name, &projectModel.Color, &projectModel.Archived, &projectModel.Position, &projectModel.CreatedAt, &projectModel.UpdatedAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project: %w", err)
+	}
+
+	return converter.ProjectModelToEntity(&projectModel), nil
+}
+
+func (r *projectRepository) GetByID(id int) (*entity.Project, error) {
+	query := projectSelect + `
+		WHERE p.id = $1
+		GROUP BY p.id
+	`
+
+	projectModel, err := scanProject(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("project not found")
+		}
+		return nil, fmt.Errorf("failed to get project by id: %w", err)
+	}
+
+	return converter.ProjectModelToEntity(projectModel), nil
+}
+
+func (r *projectRepository) GetByUserID(userID int, includeArchived bool) ([]*entity.Project, error) {
+	query := projectSelect + `
+		WHERE p.user_id = $1 AND ($2 OR NOT p.archived)
+		GROUP BY p.id
+		ORDER BY p.position, p.created_at
+	`
+
+	rows, err := r.db.Query(query, userID, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects by user id: %w", err)
+	}
+	defer rows.Close()
+
+	var projectModels []*model.ProjectModel
+	for rows.Next() {
+		projectModel, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		projectModels = append(projectModels, projectModel)
+	}
+
+	return converter.ProjectModelsToEntities(projectModels), nil
+}
+
+func (r *projectRepository) Update(project *entity.Project) (*entity.Project, error) {
+	query := `
+		UPDATE projects
+		SET name = $2, color = $3, archived = $4, position = $5, updated_at = $6
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(query, project.ID, project.Name, project.Color, project.Archived, project.Position, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update project: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("project not found")
+	}
+
+	return r.GetByID(project.ID)
+}
+
+// Delete keeps the project's todos; they no longer belong to a project.
+func (r *projectRepository) Delete(id int) error {
+	query := `DELETE FROM projects WHERE id = $1`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("project not found")
+	}
+
+	return nil
+}
+
+func (r *projectRepository) CountOpenTodos(id int) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM todos
+		WHERE project_id = $1 AND status IN ('pending', 'in_progress', 'blocked') AND deleted_at IS NULL
+	`
+
+	var count int
+	if err := r.db.QueryRow(query, id).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count open todos: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model/converter"
+)
+
+// ErrRoleNotFound is returned when no role has the requested name.
+var ErrRoleNotFound = errors.New("role not found")
+
+type RoleRepository interface {
+	GetAll() ([]*entity.RoleDefinition, error)
+	GetByName(name entity.Role) (*entity.RoleDefinition, error)
+	Save(role *entity.RoleDefinition) (*entity.RoleDefinition, error)
+	Delete(name entity.Role) error
+}
+
+type roleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) GetAll() ([]*entity.RoleDefinition, error) {
+	query := `
+		SELECT r.name, r.description, COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_name = r.name
+		GROUP BY r.name, r.description
+		ORDER BY r.name
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roleModels []*model.RoleModel
+	for rows.Next() {
+		var roleModel model.RoleModel
+		err := rows.Scan(&roleModel.Name, &roleModel.Description, &roleModel.Permissions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		roleModels = append(roleModels, &roleModel)
+	}
+
+	return converter.RoleModelsToEntities(roleModels), nil
+}
+
+func (r *roleRepository) GetByName(name entity.Role) (*entity.RoleDefinition, error) {
+	query := `
+		SELECT r.name, r.description, COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_name = r.name
+		WHERE r.name = $1
+		GROUP BY r.name, r.description
+	`
+
+	var roleModel model.RoleModel
+	err := r.db.QueryRow(query, string(name)).
+		Scan(&roleModel.Name, &roleModel.Description, &roleModel.Permissions)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRoleNotFound
+		}
+		return nil, fmt.Errorf("failed to get role by name: %w", err)
+	}
+
+	return converter.RoleModelToEntity(&roleModel), nil
+}
+
+// Save creates the role or replaces its description and permissions.
+func (r *roleRepository) Save(role *entity.RoleDefinition) (*entity.RoleDefinition, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.Exec(`
+		INSERT INTO roles (name, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description, updated_at = EXCLUDED.updated_at
+	`, string(role.Name), role.Description, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save role: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_name = $1`, string(role.Name)); err != nil {
+		return nil, fmt.Errorf("failed to clear role permissions: %w", err)
+	}
+
+	for _, permission := range role.Permissions {
+		_, err := tx.Exec(`INSERT INTO role_permissions (role_name, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING`, string(role.Name), string(permission))
+		if err != nil {
+			return nil, fmt.Errorf("failed to save role permission: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit role: %w", err)
+	}
+
+	return r.GetByName(role.Name)
+}
+
+func (r *roleRepository) Delete(name entity.Role) error {
+	query := `DELETE FROM roles WHERE name = $1`
+
+	result, err := r.db.Exec(query, string(name))
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrRoleNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model/converter"
+)
+
+type SessionRepository interface {
+	Create(session *entity.Session) (*entity.Session, error)
+	GetByID(id int) (*entity.Session, error)
+	GetActiveByUserID(userID int) ([]*entity.Session, error)
+	Touch(id int, at time.Time) error
+	Revoke(id, userID int) error
+	RevokeAllByUserID(userID int) error
+}
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// lastSeenResolution limits how often Touch writes for a busy session.
+const lastSeenResolution = time.Minute
+
+func (r *sessionRepository) Create(session *entity.Session) (*entity.Session, error) {
+	query := `
+		INSERT INTO sessions (user_id, ip_address, user_agent, created_at, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, $4, $4, $5)
+		RETURNING id, user_id, ip_address, user_agent, created_at, last_seen_at, expires_at, revoked_at
+	`
+
+	var sessionModel model.SessionModel
+	err := r.db.QueryRow(query, session.UserID, session.IPAddress, session.UserAgent, time.Now(), session.ExpiresAt).
+		Scan(&sessionModel.ID, &sessionModel.UserID, &sessionModel.IPAddress, &sessionModel.UserAgent, &sessionModel.CreatedAt, &sessionModel.LastSeenAt, &sessionModel.ExpiresAt, &sessionModel.RevokedAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return converter.SessionModelToEntity(&sessionModel), nil
+}
+
+func (r *sessionRepository) GetByID(id int) (*entity.Session, error) {
+	query := `
+		SELECT id, user_id, ip_address, user_agent, created_at, last_seen_at, expires_at, revoked_at
+		FROM sessions
+		WHERE id = $1
+	`
+
+	var sessionModel model.SessionModel
+	err := r.db.QueryRow(query, id).
+		Scan(&sessionModel.ID, &sessionModel.UserID, &sessionModel.IPAddress, &sessionModel.UserAgent, &sessionModel.CreatedAt, &sessionModel.LastSeenAt, &sessionModel.ExpiresAt, &sessionModel.RevokedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session not found")
+		}
+		return nil, fmt.Errorf("failed to get session by id: %w", err)
+	}
+
+	return converter.SessionModelToEntity(&sessionModel), nil
+}
+
+func (r *sessionRepository) GetActiveByUserID(userID int) ([]*entity.Session, error) {
+	query := `
+		SELECT id, user_id, ip_address, user_agent, created_at, last_seen_at, expires_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := r.db.Query(query, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions by user id: %w", err)
+	}
+	defer rows.Close()
+
+	var sessionModels []*model.SessionModel
+	for rows.Next() {
+		var sessionModel model.SessionModel
+		err := rows.Scan(&sessionModel.ID, &sessionModel.UserID, &sessionModel.IPAddress, &sessionModel.UserAgent, &sessionModel.CreatedAt, &sessionModel.LastSeenAt, &sessionModel.ExpiresAt, &sessionModel.RevokedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessionModels = append(sessionModels, &sessionModel)
+	}
+
+	return converter.SessionModelsToEntities(sessionModels), nil
+}
+
+// Touch records activity on the session. Writes are skipped while the stored
+// value is less than lastSeenResolution old.
+func (r *sessionRepository) Touch(id int, at time.Time) error {
+	query := `UPDATE sessions SET last_seen_at = $2 WHERE id = $1 AND last_seen_at < $3`
+
+	if _, err := r.db.Exec(query, id, at, at.Add(-lastSeenResolution)); err != nil {
+		return fmt.Errorf("failed to update session last seen: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) Revoke(id, userID int) error {
+	query := `UPDATE sessions SET revoked_at = $3 WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	result, err := r.db.Exec(query, id, userID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("session not found")
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) RevokeAllByUserID(userID int) error {
+	query := `UPDATE sessions SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL`
+
+	if _, err := r.db.Exec(query, userID, time.Now()); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
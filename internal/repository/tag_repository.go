@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model/converter"
+)
+
+type TagRepository interface {
+	Create(tag *entity.Tag) (*entity.Tag, error)
+	GetByID(id int) (*entity.Tag, error)
+	GetByUserID(userID int) ([]*entity.Tag, error)
+	GetByUserIDAndName(userID int, name string) (*entity.Tag, error)
+	Update(tag *entity.Tag) (*entity.Tag, error)
+	Delete(id int) error
+}
+
+type tagRepository struct {
+	db *sql.DB
+}
+
+func NewTagRepository(db *sql.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r *tagRepository) Create(tag *entity.Tag) (*entity.Tag, error) {
+	query := `
+		INSERT INTO tags (user_id, name, color, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, user_id, name, color, created_at
+	`
+
+	var tagModel model.TagModel
+	err := r.db.QueryRow(query, tag.UserID, tag.Name, tag.Color, time.Now()).
+		Scan(&tagModel.ID, &tagModel.UserID, &tagModel.Name, &tagModel.Color, &tagModel.CreatedAt)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return converter.TagModelToEntity(&tagModel), nil
+}
+
+func (r *tagRepository) GetByID(id int) (*entity.Tag, error) {
+	query := `
+		SELECT id, user_id, name, color, created_at
+		FROM tags
+		WHERE id = $1
+	`
+
+	var tagModel model.TagModel
+	err := r.db.QueryRow(query, id).
+		Scan(&tagModel.ID, &tagModel.UserID, &tagModel.Name, &tagModel.Color, &tagModel.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("tag not found")
+		}
+		return nil, fmt.Errorf("failed to get tag by id: %w", err)
+	}
+
+	return converter.TagModelToEntity(&tagModel), nil
+}
+
+func (r *tagRepository) GetByUserID(userID int) ([]*entity.Tag, error) {
+	query := `
+		SELECT id, user_id, name, color, created_at
+		FROM tags
+		WHERE user_id = $1
+		ORDER BY LOWER(name)
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags by user id: %w", err)
+	}
+	defer rows.Close()
+
+	var tagModels []*model.TagModel
+	for rows.Next() {
+		var tagModel model.TagModel
+		err := rows.Scan(&tagModel.ID, &tagModel.UserID, &tagModel.Name, &tagModel.Color, &tagModel.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tagModels = append(tagModels, &tagModel)
+	}
+
+	return converter.TagModelsToEntities(tagModels), nil
+}
+
+// GetByUserIDAndName looks the name up case-insensitively.
+func (r *tagRepository) GetByUserIDAndName(userID int, name string) (*entity.Tag, error) {
+	query := `
+		SELECT id, user_id, name, color, created_at
+		FROM tags
+		WHERE user_id = $1 AND LOWER(name) = LOWER($2)
+	`
+
+	var tagModel model.TagModel
+	err := r.db.QueryRow(query, userID, name).
+		Scan(&tagModel.ID, &tagModel.UserID, &tagModel.Name, &tagModel.Color, &tagModel.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("tag not found")
+		}
+		return nil, fmt.Errorf("failed to get tag by name: %w", err)
+	}
+
+	return converter.TagModelToEntity(&tagModel), nil
+}
+
+func (r *tagRepository) Update(tag *entity.Tag) (*entity.Tag, error) {
+	query := `
+		UPDATE tags
+		SET name = $2, color = $3
+		WHERE id = $1
+		RETURNING id, user_id, name, color, created_at
+	`
+
+	var tagModel model.TagModel
+	err := r.db.QueryRow(query, tag.ID, tag.Name, tag.Color).
+		Scan(&tagModel.ID, &tagModel.UserID, &tagModel.Name, &tagModel.Color, &tagModel.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("tag not found")
+		}
+		return nil, fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	return converter.TagModelToEntity(&tagModel), nil
+}
+
+// Delete removes the tag from every todo it is attached to.
+func (r *tagRepository) Delete(id int) error {
+	query := `DELETE FROM tags WHERE id = $1`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag not found")
+	}
+
+	return nil
+}
@@ -2,63 +2,126 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"html"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
+
 	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/model/converter"
 )
 
+// ErrTodoVersionMismatch is returned when a todo changed after it was read.
+var ErrTodoVersionMismatch = errors.New("todo was modified by another request")
+
 type TodoRepository interface {
 	Create(todo *entity.Todo) (*entity.Todo, error)
 	GetByID(id int) (*entity.Todo, error)
 	GetByUserID(userID int) ([]*entity.Todo, error)
 	GetAll() ([]*entity.Todo, error)
+	// Update saves the todo when its stored version still equals
+	// todo.Version and increments the version.
 	Update(todo *entity.Todo) (*entity.Todo, error)
-	Delete(id int) error
+	// Delete moves a todo at the given version to the trash along with its
+	// subtasks, whose IDs it returns; Purge removes them for good.
+	Delete(id, version int) (subtaskIDs []int, err error)
+	GetDeletedByID(id int) (*entity.Todo, error)
+	// GetDeletedByUserID returns the user's trash, most recently deleted
+	// first.
+	GetDeletedByUserID(userID int) ([]*entity.Todo, error)
+	// Restore takes a todo out of the trash along with the subtasks deleted
+	// together with it, whose IDs it returns.
+	Restore(id int) (todo *entity.Todo, subtaskIDs []int, err error)
+	// Purge permanently removes todos deleted before the given time.
+	Purge(deletedBefore time.Time) (int64, error)
 	GetByIDAndUserID(id, userID int) (*entity.Todo, error)
+	// GetByUserIDAndTags returns the user's todos carrying any of the tag
+	// names, or all of them with matchAll.
+	GetByUserIDAndTags(userID int, tagNames []string, matchAll bool) ([]*entity.Todo, error)
+	GetByProjectID(projectID int) ([]*entity.Todo, error)
+	// GetByParentID returns the subtasks of a todo in order.
+	GetByParentID(parentID int) ([]*entity.Todo, error)
+	AttachTag(todoID, tagID int) error
+	DetachTag(todoID, tagID int) error
+	// Search returns the user's todos matching a web-style search query,
+	// best match first.
+	Search(userID int, query string, limit int) ([]*entity.TodoSearchResult, error)
+	// SearchAll searches the todos of every user.
+	SearchAll(query string, limit int) ([]*entity.TodoSearchResult, error)
+}
+
+// searchHeadline configures the ts_headline snippets. Matches are delimited
+// by control characters, which are stripped from the text beforehand, so
+// highlight can escape the rest before turning them into <mark> tags.
+const searchHeadline = `'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MaxWords=20, MinWords=5'`
+
+const (
+	searchMatchStart = "\x02"
+	searchMatchStop  = "\x03"
+)
+
+var searchMatchReplacer = strings.NewReplacer(searchMatchStart, "<mark>", searchMatchStop, "</mark>")
+
+// highlight turns a ts_headline snippet into HTML safe to show as is.
+func highlight(snippet string) string {
+	return searchMatchReplacer.Replace(html.EscapeString(snippet))
+}
+
+// todoColumns is the column list scanTodo expects.
+const todoColumns = `id, user_id, project_id, parent_id, position, title, description, status, completed_at, due_at, timezone, recurrence, occurrence, deleted_at, version, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTodo reads todoColumns followed by any extra columns into extra.
+func scanTodo(row rowScanner, extra ...interface{}) (*model.TodoModel, error) {
+	var todoModel model.TodoModel
+	dest := []interface{}{&todoModel.ID, &todoModel.UserID, &todoModel.ProjectID, &todoModel.ParentID, &todoModel.Position, &todoModel.Title, &todoModel.Description, &todoModel.Status, &todoModel.CompletedAt, &todoModel.DueAt, &todoModel.Timezone, &todoModel.Recurrence, &todoModel.Occurrence, &todoModel.DeletedAt, &todoModel.Version, &todoModel.CreatedAt, &todoModel.UpdatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &todoModel, nil
 }
 
 type todoRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewTodoRepository(db *sql.DB) TodoRepository {
+// NewTodoRepository works on a database or, for use within a Transactor, a
+// transaction.
+func NewTodoRepository(db DBTX) TodoRepository {
 	return &todoRepository{db: db}
 }
 
 func (r *todoRepository) Create(todo *entity.Todo) (*entity.Todo, error) {
 	query := `
-		INSERT INTO todos (user_id, title, description, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, user_id, title, description, status, created_at, updated_at
-	`
+		INSERT INTO todos (user_id, project_id, parent_id, position, title, description, status, completed_at, due_at, timezone, recurrence, occurrence, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING ` + todoColumns
 
 	now := time.Now()
-	var todoModel model.TodoModel
-
-	err := r.db.QueryRow(query, todo.UserID, todo.Title, todo.Description, string(todo.Status), now, now).
-		Scan(&todoModel.ID, &todoModel.UserID, &todoModel.Title, &todoModel.Description, &todoModel.Status, &todoModel.CreatedAt, &todoModel.UpdatedAt)
-
+	todoModel, err := scanTodo(r.db.QueryRow(query, todo.UserID, todo.ProjectID, todo.ParentID, todo.Position, todo.Title, todo.Description, string(todo.Status), todo.CompletedAt, todo.DueAt, todo.Timezone, todo.Recurrence, todo.Occurrence, now, now))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create todo: %w", err)
 	}
 
-	return converter.TodoModelToEntity(&todoModel), nil
+	return converter.TodoModelToEntity(todoModel), nil
 }
 
 func (r *todoRepository) GetByID(id int) (*entity.Todo, error) {
 	query := `
-		SELECT id, user_id, title, description, status, created_at, updated_at
+		SELECT ` + todoColumns + `
 		FROM todos
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
-	var todoModel model.TodoModel
-	err := r.db.QueryRow(query, id).
-		Scan(&todoModel.ID, &todoModel.UserID, &todoModel.Title, &todoModel.Description, &todoModel.Status, &todoModel.CreatedAt, &todoModel.UpdatedAt)
-
+	todoModel, err := scanTodo(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("todo not found")
@@ -66,14 +129,14 @@ func (r *todoRepository) GetByID(id int) (*entity.Todo, error) {
 		return nil, fmt.Errorf("failed to get todo by id: %w", err)
 	}
 
-	return converter.TodoModelToEntity(&todoModel), nil
+	return r.withTags(converter.TodoModelToEntity(todoModel))
 }
 
 func (r *todoRepository) GetByUserID(userID int) ([]*entity.Todo, error) {
 	query := `
-		SELECT id, user_id, title, description, status, created_at, updated_at
+		SELECT ` + todoColumns + `
 		FROM todos
-		WHERE user_id = $1
+		WHERE user_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
@@ -83,23 +146,23 @@ func (r *todoRepository) GetByUserID(userID int) ([]*entity.Todo, error) {
 	}
 	defer rows.Close()
 
-	var todoModels []*model.TodoModel
-	for rows.Next() {
-		var todoModel model.TodoModel
-		err := rows.Scan(&todoModel.ID, &todoModel.UserID, &todoModel.Title, &todoModel.Description, &todoModel.Status, &todoModel.CreatedAt, &todoModel.UpdatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan todo: %w", err)
-		}
-		todoModels = append(todoModels, &todoModel)
+	todos, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	return converter.TodoModelsToEntities(todoModels), nil
+	if err := r.loadTags(todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
 }
 
 func (r *todoRepository) GetAll() ([]*entity.Todo, error) {
 	query := `
-		SELECT id, user_id, title, description, status, created_at, updated_at
+		SELECT ` + todoColumns + `
 		FROM todos
+		WHERE deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
@@ -109,49 +172,321 @@ func (r *todoRepository) GetAll() ([]*entity.Todo, error) {
 	}
 	defer rows.Close()
 
-	var todoModels []*model.TodoModel
-	for rows.Next() {
-		var todoModel model.TodoModel
-		err := rows.Scan(&todoModel.ID, &todoModel.UserID, &todoModel.Title, &todoModel.Description, &todoModel.Status, &todoModel.CreatedAt, &todoModel.UpdatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan todo: %w", err)
-		}
-		todoModels = append(todoModels, &todoModel)
+	todos, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	return converter.TodoModelsToEntities(todoModels), nil
+	if err := r.loadTags(todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
 }
 
 func (r *todoRepository) Update(todo *entity.Todo) (*entity.Todo, error) {
 	query := `
 		UPDATE todos
-		SET title = $2, description = $3, status = $4, updated_at = $5
-		WHERE id = $1
-		RETURNING id, user_id, title, description, status, created_at, updated_at
-	`
+		SET project_id = $2, position = $3, title = $4, description = $5, status = $6, completed_at = $7,
+			due_at = $8, timezone = $9, recurrence = $10, updated_at = $11, version = version + 1
+		WHERE id = $1 AND version = $12 AND deleted_at IS NULL
+		RETURNING ` + todoColumns
 
 	now := time.Now()
-	var todoModel model.TodoModel
+	todoModel, err := scanTodo(r.db.QueryRow(query, todo.ID, todo.ProjectID, todo.Position, todo.Title, todo.Description, string(todo.Status), todo.CompletedAt, todo.DueAt, todo.Timezone, todo.Recurrence, now, todo.Version))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, r.missingOrChanged(todo.ID)
+		}
+		return nil, fmt.Errorf("failed to update todo: %w", err)
+	}
+
+	return r.withTags(converter.TodoModelToEntity(todoModel))
+}
+
+// Delete moves a todo and its subtasks to the trash. They share the deletion
+// time, which Restore uses to bring the subtasks back with their parent.
+func (r *todoRepository) Delete(id, version int) ([]int, error) {
+	query := `
+		UPDATE todos SET deleted_at = $2, version = version + 1
+		WHERE (id = $1 OR parent_id = $1) AND deleted_at IS NULL
+			AND EXISTS (SELECT 1 FROM todos WHERE id = $1 AND version = $3 AND deleted_at IS NULL)
+		RETURNING id
+	`
+
+	ids, err := r.queryIDs(query, id, time.Now(), version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete todo: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, r.missingOrChanged(id)
+	}
+
+	return withoutID(ids, id), nil
+}
+
+// queryIDs runs a statement returning a single id column.
+func (r *todoRepository) queryIDs(query string, args ...interface{}) ([]int, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func withoutID(ids []int, id int) []int {
+	var rest []int
+	for _, other := range ids {
+		if other != id {
+			rest = append(rest, other)
+		}
+	}
+	return rest
+}
+
+// missingOrChanged explains why a versioned write matched no row.
+func (r *todoRepository) missingOrChanged(id int) error {
+	var exists bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM todos WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check todo: %w", err)
+	}
+	if exists {
+		return ErrTodoVersionMismatch
+	}
+	return fmt.Errorf("todo not found")
+}
 
-	err := r.db.QueryRow(query, todo.ID, todo.Title, todo.Description, string(todo.Status), now).
-		Scan(&todoModel.ID, &todoModel.UserID, &todoModel.Title, &todoModel.Description, &todoModel.Status, &todoModel.CreatedAt, &todoModel.UpdatedAt)
+func (r *todoRepository) GetDeletedByID(id int) (*entity.Todo, error) {
+	query := `
+		SELECT ` + todoColumns + `
+		FROM todos
+		WHERE id = $1 AND deleted_at IS NOT NULL
+	`
 
+	todoModel, err := scanTodo(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("todo not found in trash")
+		}
+		return nil, fmt.Errorf("failed to get deleted todo: %w", err)
+	}
+
+	return r.withTags(converter.TodoModelToEntity(todoModel))
+}
+
+func (r *todoRepository) GetDeletedByUserID(userID int) ([]*entity.Todo, error) {
+	query := `
+		SELECT ` + todoColumns + `
+		FROM todos
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted todos: %w", err)
+	}
+	defer rows.Close()
+
+	todos, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadTags(todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+func (r *todoRepository) Restore(id int) (*entity.Todo, []int, error) {
+	query := `
+		UPDATE todos SET deleted_at = NULL, updated_at = $2, version = version + 1
+		WHERE deleted_at IS NOT NULL AND (
+			id = $1 OR
+			(parent_id = $1 AND deleted_at = (SELECT deleted_at FROM todos WHERE id = $1))
+		)
+		RETURNING id
+	`
+
+	ids, err := r.queryIDs(query, id, time.Now())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to restore todo: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil, fmt.Errorf("todo not found in trash")
+	}
+
+	todo, err := r.GetByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return todo, withoutID(ids, id), nil
+}
+
+func (r *todoRepository) Purge(deletedBefore time.Time) (int64, error) {
+	query := `DELETE FROM todos WHERE deleted_at < $1`
+
+	result, err := r.db.Exec(query, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted todos: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *todoRepository) GetByIDAndUserID(id, userID int) (*entity.Todo, error) {
+	query := `
+		SELECT ` + todoColumns + `
+		FROM todos
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`
+
+	todoModel, err := scanTodo(r.db.QueryRow(query, id, userID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("todo not found")
 		}
-		return nil, fmt.Errorf("failed to update todo: %w", err)
+		return nil, fmt.Errorf("failed to get todo by id and user id: %w", err)
 	}
 
-	return converter.TodoModelToEntity(&todoModel), nil
+	return r.withTags(converter.TodoModelToEntity(todoModel))
 }
 
-func (r *todoRepository) Delete(id int) error {
-	query := `DELETE FROM todos WHERE id = $1`
+func (r *todoRepository) GetByUserIDAndTags(userID int, tagNames []string, matchAll bool) ([]*entity.Todo, error) {
+	names := make([]string, 0, len(tagNames))
+	seen := make(map[string]bool, len(tagNames))
+	for _, name := range tagNames {
+		name = strings.ToLower(name)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	// Without matchAll any single matching tag is enough
+	required := 1
+	if matchAll {
+		required = len(names)
+	}
+
+	query := `
+		SELECT ` + todoColumns + `
+		FROM todos
+		WHERE user_id = $1 AND deleted_at IS NULL AND id IN (
+			SELECT tt.todo_id
+			FROM todo_tags tt
+			JOIN tags t ON t.id = tt.tag_id
+			WHERE t.user_id = $1 AND LOWER(t.name) = ANY($2)
+			GROUP BY tt.todo_id
+			HAVING COUNT(*) >= $3
+		)
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, userID, pq.Array(names), required)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todos by tags: %w", err)
+	}
+	defer rows.Close()
 
-	result, err := r.db.Exec(query, id)
+	todos, err := scanTodos(rows)
 	if err != nil {
-		return fmt.Errorf("failed to delete todo: %w", err)
+		return nil, err
+	}
+
+	if err := r.loadTags(todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+func (r *todoRepository) GetByProjectID(projectID int) ([]*entity.Todo, error) {
+	query := `
+		SELECT ` + todoColumns + `
+		FROM todos
+		WHERE project_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todos by project id: %w", err)
+	}
+	defer rows.Close()
+
+	todos, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadTags(todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+func (r *todoRepository) GetByParentID(parentID int) ([]*entity.Todo, error) {
+	query := `
+		SELECT ` + todoColumns + `
+		FROM todos
+		WHERE parent_id = $1 AND deleted_at IS NULL
+		ORDER BY position, id
+	`
+
+	rows, err := r.db.Query(query, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todos by parent id: %w", err)
+	}
+	defer rows.Close()
+
+	todos, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadTags(todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// AttachTag is a no-op when the tag is already attached.
+func (r *todoRepository) AttachTag(todoID, tagID int) error {
+	query := `
+		INSERT INTO todo_tags (todo_id, tag_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := r.db.Exec(query, todoID, tagID); err != nil {
+		return fmt.Errorf("failed to attach tag: %w", err)
+	}
+
+	return nil
+}
+
+func (r *todoRepository) DetachTag(todoID, tagID int) error {
+	query := `DELETE FROM todo_tags WHERE todo_id = $1 AND tag_id = $2`
+
+	result, err := r.db.Exec(query, todoID, tagID)
+	if err != nil {
+		return fmt.Errorf("failed to detach tag: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -160,29 +495,129 @@ func (r *todoRepository) Delete(id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("todo not found")
+		return fmt.Errorf("tag is not attached to the todo")
 	}
 
 	return nil
 }
 
-func (r *todoRepository) GetByIDAndUserID(id, userID int) (*entity.Todo, error) {
+func (r *todoRepository) withTags(todo *entity.Todo) (*entity.Todo, error) {
+	if err := r.loadTags([]*entity.Todo{todo}); err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
+
+// loadTags fills in the tags of todos with a single query.
+func (r *todoRepository) loadTags(todos []*entity.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*entity.Todo, len(todos))
+	ids := make([]int64, len(todos))
+	for i, todo := range todos {
+		byID[todo.ID] = todo
+		ids[i] = int64(todo.ID)
+	}
+
 	query := `
-		SELECT id, user_id, title, description, status, created_at, updated_at
-		FROM todos
-		WHERE id = $1 AND user_id = $2
+		SELECT tt.todo_id, t.id, t.user_id, t.name, t.color, t.created_at
+		FROM todo_tags tt
+		JOIN tags t ON t.id = tt.tag_id
+		WHERE tt.todo_id = ANY($1)
+		ORDER BY LOWER(t.name)
 	`
 
-	var todoModel model.TodoModel
-	err := r.db.QueryRow(query, id, userID).
-		Scan(&todoModel.ID, &todoModel.UserID, &todoModel.Title, &todoModel.Description, &todoModel.Status, &todoModel.CreatedAt, &todoModel.UpdatedAt)
+	rows, err := r.db.Query(query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to load todo tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var todoID int
+		var tagModel model.TagModel
+		err := rows.Scan(&todoID, &tagModel.ID, &tagModel.UserID, &tagModel.Name, &tagModel.Color, &tagModel.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to scan todo tag: %w", err)
+		}
+		if todo, ok := byID[todoID]; ok {
+			todo.Tags = append(todo.Tags, *converter.TagModelToEntity(&tagModel))
+		}
+	}
+
+	return rows.Err()
+}
 
+func scanTodos(rows *sql.Rows) ([]*entity.Todo, error) {
+	var todoModels []*model.TodoModel
+	for rows.Next() {
+		todoModel, err := scanTodo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan todo: %w", err)
+		}
+		todoModels = append(todoModels, todoModel)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read todos: %w", err)
+	}
+
+	return converter.TodoModelsToEntities(todoModels), nil
+}
+
+func (r *todoRepository) Search(userID int, query string, limit int) ([]*entity.TodoSearchResult, error) {
+	return r.search("AND user_id = $3", query, limit, userID)
+}
+
+func (r *todoRepository) SearchAll(query string, limit int) ([]*entity.TodoSearchResult, error) {
+	return r.search("", query, limit)
+}
+
+// search runs a ranked full-text query; filter narrows it with arguments
+// from $3 on.
+func (r *todoRepository) search(filter, query string, limit int, args ...interface{}) ([]*entity.TodoSearchResult, error) {
+	sqlQuery := `
+		SELECT ` + todoColumns + `, rank,
+			ts_headline('english', translate(title, chr(2) || chr(3), ''), q, ` + searchHeadline + `),
+			ts_headline('english', translate(description, chr(2) || chr(3), ''), q, ` + searchHeadline + `)
+		FROM (
+			SELECT todos.*, q, ts_rank_cd(search_vector, q) AS rank
+			FROM todos, websearch_to_tsquery('english', $1) AS q
+			WHERE search_vector @@ q AND deleted_at IS NULL ` + filter + `
+			ORDER BY rank DESC, created_at DESC
+			LIMIT $2
+		) AS matches
+		ORDER BY rank DESC, created_at DESC
+	`
+
+	rows, err := r.db.Query(sqlQuery, append([]interface{}{query, limit}, args...)...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("todo not found")
+		return nil, fmt.Errorf("failed to search todos: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*entity.TodoSearchResult
+	var todos []*entity.Todo
+	for rows.Next() {
+		result := &entity.TodoSearchResult{}
+		todoModel, err := scanTodo(rows, &result.Rank, &result.TitleSnippet, &result.DescriptionSnippet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
 		}
-		return nil, fmt.Errorf("failed to get todo by id and user id: %w", err)
+		result.Todo = converter.TodoModelToEntity(todoModel)
+		result.TitleSnippet = highlight(result.TitleSnippet)
+		result.DescriptionSnippet = highlight(result.DescriptionSnippet)
+		results = append(results, result)
+		todos = append(todos, result.Todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read search results: %w", err)
+	}
+
+	if err := r.loadTags(todos); err != nil {
+		return nil, err
 	}
 
-	return converter.TodoModelToEntity(&todoModel), nil
+	return results, nil
 }
@@ -0,0 +1,25 @@
+package repository
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet string
+		want    string
+	}{
+		{"plain text", "buy milk", "buy milk"},
+		{"match", "buy \x02milk\x03 today", "buy <mark>milk</mark> today"},
+		{"markup is escaped", "\x02<script>\x03alert(1)</script>", "<mark>&lt;script&gt;</mark>alert(1)&lt;/script&gt;"},
+		{"literal mark tags are escaped", "<mark>milk</mark>", "&lt;mark&gt;milk&lt;/mark&gt;"},
+		{"attributes are escaped", "<img src=x onerror=\"\x02alert\x03(1)\">", "&lt;img src=x onerror=&#34;<mark>alert</mark>(1)&#34;&gt;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlight(tt.snippet); got != tt.want {
+				t.Errorf("highlight(%q) = %q, want %q", tt.snippet, got, tt.want)
+			}
+		})
+	}
+}
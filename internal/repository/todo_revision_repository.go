@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model/converter"
+)
+
+type TodoRevisionRepository interface {
+	Create(revision *entity.TodoRevision) error
+	// GetByTodoID returns the revisions of a todo, oldest first.
+	GetByTodoID(todoID int) ([]*entity.TodoRevision, error)
+}
+
+type todoRevisionRepository struct {
+	db DBTX
+}
+
+func NewTodoRevisionRepository(db DBTX) TodoRevisionRepository {
+	return &todoRevisionRepository{db: db}
+}
+
+func (r *todoRevisionRepository) Create(revision *entity.TodoRevision) error {
+	query := `
+		INSERT INTO todo_revisions (todo_id, actor_user_id, impersonator_id, admin_action, action, changes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	changes, err := json.Marshal(revision.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode revision changes: %w", err)
+	}
+
+	impersonatorID := sql.NullInt64{Int64: int64(revision.ImpersonatorID), Valid: revision.ImpersonatorID != 0}
+
+	_, err = r.db.Exec(query, revision.TodoID, revision.ActorUserID, impersonatorID, revision.AdminAction, string(revision.Action), string(changes), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to create todo revision: %w", err)
+	}
+
+	return nil
+}
+
+func (r *todoRevisionRepository) GetByTodoID(todoID int) ([]*entity.TodoRevision, error) {
+	query := `
+		SELECT r.id, r.todo_id, r.actor_user_id, COALESCE(u.username, ''), r.impersonator_id, r.admin_action, r.action, r.changes, r.created_at
+		FROM todo_revisions r
+		LEFT JOIN users u ON u.id = r.actor_user_id
+		WHERE r.todo_id = $1
+		ORDER BY r.created_at, r.id
+	`
+
+	rows, err := r.db.Query(query, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todo revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []*entity.TodoRevision{}
+	for rows.Next() {
+		var revisionModel model.TodoRevisionModel
+		err := rows.Scan(&revisionModel.ID, &revisionModel.TodoID, &revisionModel.ActorUserID, &revisionModel.ActorUsername, &revisionModel.ImpersonatorID, &revisionModel.AdminAction, &revisionModel.Action, &revisionModel.Changes, &revisionModel.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan todo revision: %w", err)
+		}
+
+		revision := converter.TodoRevisionModelToEntity(&revisionModel)
+		if err := json.Unmarshal(revisionModel.Changes, &revision.Changes); err != nil {
+			return nil, fmt.Errorf("failed to decode revision changes: %w", err)
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read todo revisions: %w", err)
+	}
+
+	return revisions, nil
+}
@@ -14,8 +14,18 @@ type UserRepository interface {
 	Create(user *entity.User) (*entity.User, error)
 	GetByID(id int) (*entity.User, error)
 	GetByUsername(username string) (*entity.User, error)
+	GetByEmail(email string) (*entity.User, error)
 	GetAll() ([]*entity.User, error)
-	Update(user *entity.User) (*entity.User, error)
+	CountByRole(role entity.Role) (int, error)
+	MostCommonPasswordHash() (string, error)
+	// The setters below change only their own columns, so concurrent
+	// changes to other fields of the same user are kept.
+	SetPassword(id int, hash string, revokeTokens bool) (*entity.User, error)
+	SetRole(id int, role entity.Role) (*entity.User, error)
+	SetEmail(id int, email string) (*entity.User, error)
+	SetEmailVerified(id int, email string, verifiedAt time.Time) (*entity.User, error)
+	SetDisabledAt(id int, disabledAt *time.Time) (*entity.User, error)
+	ReplacePasswordHash(id int, oldHash, newHash string) (bool, error)
 	Delete(id int) error
 }
 
@@ -29,16 +39,16 @@ func NewUserRepository(db *sql.DB) UserRepository {
 
 func (r *userRepository) Create(user *entity.User) (*entity.User, error) {
 	query := `
-		INSERT INTO users (username, password, role, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, username, password, role, created_at, updated_at
+		INSERT INTO users (username, password, role, email, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, username, password, role, email, email_verified_at, token_version, disabled_at, created_at, updated_at
 	`
 
 	now := time.Now()
 	var userModel model.UserModel
 
-	err := r.db.QueryRow(query, user.Username, user.Password, string(user.Role), now, now).
-		Scan(&userModel.ID, &userModel.Username, &userModel.Password, &userModel.Role, &userModel.CreatedAt, &userModel.UpdatedAt)
+	err := r.db.QueryRow(query, user.Username, user.Password, string(user.Role), nullString(user.Email), now, now).
+		Scan(&userModel.ID, &userModel.Username, &userModel.Password, &userModel.Role, &userModel.Email, &userModel.EmailVerifiedAt, &userModel.TokenVersion, &userModel.DisabledAt, &userModel.CreatedAt, &userModel.UpdatedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
@@ -49,14 +59,14 @@ func (r *userRepository) Create(user *entity.User) (*entity.User, error) {
 
 func (r *userRepository) GetByID(id int) (*entity.User, error) {
 	query := `
-		SELECT id, username, password, role, created_at, updated_at
+		SELECT id, username, password, role, email, email_verified_at, token_version, disabled_at, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
 
 	var userModel model.UserModel
 	err := r.db.QueryRow(query, id).
-		Scan(&userModel.ID, &userModel.Username, &userModel.Password, &userModel.Role, &userModel.CreatedAt, &userModel.UpdatedAt)
+		Scan(&userModel.ID, &userModel.Username, &userModel.Password, &userModel.Role, &userModel.Email, &userModel.EmailVerifiedAt, &userModel.TokenVersion, &userModel.DisabledAt, &userModel.CreatedAt, &userModel.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -70,14 +80,14 @@ func (r *userRepository) GetByID(id int) (*entity.User, error) {
 
 func (r *userRepository) GetByUsername(username string) (*entity.User, error) {
 	query := `
-		SELECT id, username, password, role, created_at, updated_at
+		SELECT id, username, password, role, email, email_verified_at, token_version, disabled_at, created_at, updated_at
 		FROM users
-		WHERE username = $1
+		WHERE LOWER(username) = LOWER($1)
 	`
 
 	var userModel model.UserModel
 	err := r.db.QueryRow(query, username).
-		Scan(&userModel.ID, &userModel.Username, &userModel.Password, &userModel.Role, &userModel.CreatedAt, &userModel.UpdatedAt)
+		Scan(&userModel.ID, &userModel.Username, &userModel.Password, &userModel.Role, &userModel.Email, &userModel.EmailVerifiedAt, &userModel.TokenVersion, &userModel.DisabledAt, &userModel.CreatedAt, &userModel.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -89,9 +99,31 @@ func (r *userRepository) GetByUsername(username string) (*entity.User, error) {
 	return converter.UserModelToEntity(&userModel), nil
 }
 
+// GetByEmail looks the address up case-insensitively.
+func (r *userRepository) GetByEmail(email string) (*entity.User, error) {
+	query := `
+		SELECT id, username, password, role, email, email_verified_at, token_version, disabled_at, created_at, updated_at
+		FROM users
+		WHERE LOWER(email) = LOWER($1)
+	`
+
+	var userModel model.UserModel
+	err := r.db.QueryRow(query, email).
+		Scan(&userModel.ID, &userModel.Username, &userModel.Password, &userModel.Role, &userModel.Email, &userModel.EmailVerifiedAt, &userModel.TokenVersion, &userModel.DisabledAt, &userModel.CreatedAt, &userModel.UpdatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+
+	return converter.UserModelToEntity(&userModel), nil
+}
+
 func (r *userRepository) GetAll() ([]*entity.User, error) {
 	query := `
-		SELECT id, username, password, role, created_at, updated_at
+		SELECT id, username, password, role, email, email_verified_at, token_version, disabled_at, created_at, updated_at
 		FROM users
 		ORDER BY created_at DESC
 	`
@@ -105,7 +137,7 @@ func (r *userRepository) GetAll() ([]*entity.User, error) {
 	var userModels []*model.UserModel
 	for rows.Next() {
 		var userModel model.UserModel
-		err := rows.Scan(&userModel.ID, &userModel.Username, &userModel.Password, &userModel.Role, &userModel.CreatedAt, &userModel.UpdatedAt)
+		err := rows.Scan(&userModel.ID, &userModel.Username, &userModel.Password, &userModel.Role, &userModel.Email, &userModel.EmailVerifiedAt, &userModel.TokenVersion, &userModel.DisabledAt, &userModel.CreatedAt, &userModel.UpdatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
@@ -115,30 +147,145 @@ func (r *userRepository) GetAll() ([]*entity.User, error) {
 	return converter.UserModelsToEntities(userModels), nil
 }
 
-func (r *userRepository) Update(user *entity.User) (*entity.User, error) {
+func (r *userRepository) CountByRole(role entity.Role) (int, error) {
+	query := `SELECT COUNT(*) FROM users WHERE role = $1`
+
+	var count int
+	if err := r.db.QueryRow(query, string(role)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users by role: %w", err)
+	}
+
+	return count, nil
+}
+
+// MostCommonPasswordHash returns a stored hash using the algorithm and
+// parameters most users' hashes use, or "" when there are no users. The
+// profile is the algorithm identifier plus, for argon2id, its parameter
+// segment and, for bcrypt, its cost segment.
+func (r *userRepository) MostCommonPasswordHash() (string, error) {
+	query := `
+		SELECT MIN(password)
+		FROM users
+		GROUP BY split_part(password, '$', 2),
+			CASE split_part(password, '$', 2)
+				WHEN 'argon2id' THEN split_part(password, '$', 4)
+				ELSE split_part(password, '$', 3)
+			END
+		ORDER BY COUNT(*) DESC
+		LIMIT 1
+	`
+
+	var hash string
+	err := r.db.QueryRow(query).Scan(&hash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get most common password hash: %w", err)
+	}
+
+	return hash, nil
+}
+
+// SetPassword stores a new hash. With revokeTokens the token version is
+// bumped in the same statement, so tokens issued before stop working.
+func (r *userRepository) SetPassword(id int, hash string, revokeTokens bool) (*entity.User, error) {
 	query := `
 		UPDATE users
-		SET username = $2, password = $3, role = $4, updated_at = $5
+		SET password = $2, token_version = token_version + CASE WHEN $3 THEN 1 ELSE 0 END, updated_at = $4
 		WHERE id = $1
-		RETURNING id, username, password, role, created_at, updated_at
+		RETURNING id, username, password, role, email, email_verified_at, token_version, disabled_at, created_at, updated_at
 	`
 
-	now := time.Now()
-	var userModel model.UserModel
+	return r.updateUser("failed to set password", query, id, hash, revokeTokens, time.Now())
+}
+
+// SetRole changes the role and bumps the token version, since tokens carry
+// the role they were issued under.
+func (r *userRepository) SetRole(id int, role entity.Role) (*entity.User, error) {
+	query := `
+		UPDATE users
+		SET role = $2, token_version = token_version + 1, updated_at = $3
+		WHERE id = $1
+		RETURNING id, username, password, role, email, email_verified_at, token_version, disabled_at, created_at, updated_at
+	`
+
+	return r.updateUser("failed to set role", query, id, string(role), time.Now())
+}
+
+// SetEmail stores a new, unverified address.
+func (r *userRepository) SetEmail(id int, email string) (*entity.User, error) {
+	query := `
+		UPDATE users
+		SET email = $2, email_verified_at = NULL, updated_at = $3
+		WHERE id = $1
+		RETURNING id, username, password, role, email, email_verified_at, token_version, disabled_at, created_at, updated_at
+	`
 
-	err := r.db.QueryRow(query, user.ID, user.Username, user.Password, string(user.Role), now).
-		Scan(&userModel.ID, &userModel.Username, &userModel.Password, &userModel.Role, &userModel.CreatedAt, &userModel.UpdatedAt)
+	return r.updateUser("failed to set email", query, id, nullString(email), time.Now())
+}
+
+// SetEmailVerified marks the address verified, but only while the user still
+// has email; an address changed in the meantime is not verified by a token
+// sent to the old one. An address verified before keeps its timestamp.
+func (r *userRepository) SetEmailVerified(id int, email string, verifiedAt time.Time) (*entity.User, error) {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, $3), updated_at = $3
+		WHERE id = $1 AND LOWER(email) = LOWER($2)
+		RETURNING id, username, password, role, email, email_verified_at, token_version, disabled_at, created_at, updated_at
+	`
+
+	return r.updateUser("failed to verify email", query, id, email, verifiedAt)
+}
+
+// SetDisabledAt disables the account at disabledAt, or enables it when nil.
+func (r *userRepository) SetDisabledAt(id int, disabledAt *time.Time) (*entity.User, error) {
+	query := `
+		UPDATE users
+		SET disabled_at = $2, updated_at = $3
+		WHERE id = $1
+		RETURNING id, username, password, role, email, email_verified_at, token_version, disabled_at, created_at, updated_at
+	`
+
+	return r.updateUser("failed to set disabled_at", query, id, disabledAt, time.Now())
+}
+
+// updateUser runs an UPDATE returning the user row.
+func (r *userRepository) updateUser(failure, query string, args ...interface{}) (*entity.User, error) {
+	var userModel model.UserModel
+	err := r.db.QueryRow(query, args...).
+		Scan(&userModel.ID, &userModel.Username, &userModel.Password, &userModel.Role, &userModel.Email, &userModel.EmailVerifiedAt, &userModel.TokenVersion, &userModel.DisabledAt, &userModel.CreatedAt, &userModel.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
 		}
-		return nil, fmt.Errorf("failed to update user: %w", err)
+		return nil, fmt.Errorf("%s: %w", failure, err)
 	}
 
 	return converter.UserModelToEntity(&userModel), nil
 }
 
+// ReplacePasswordHash swaps the stored hash only while it still equals
+// oldHash, so it changes nothing else about the user and never overwrites a
+// password set in the meantime. It reports whether the hash was replaced.
+func (r *userRepository) ReplacePasswordHash(id int, oldHash, newHash string) (bool, error) {
+	query := `UPDATE users SET password = $2 WHERE id = $1 AND password = $3`
+
+	result, err := r.db.Exec(query, id, newHash, oldHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to replace password hash: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
 func (r *userRepository) Delete(id int) error {
 	query := `DELETE FROM users WHERE id = $1`
 
@@ -158,3 +305,8 @@ func (r *userRepository) Delete(id int) error {
 
 	return nil
 }
+
+// nullString stores empty strings as NULL, which unique indexes ignore.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,382 @@
+// Package rrule implements the subset of RFC 5545 recurrence rules needed for
+// repeating todos: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT,
+// UNTIL, BYDAY, BYMONTHDAY and BYMONTH. Weeks start on Monday.
+//
+// Occurrences keep the wall-clock time of the first one in its location, so
+// a todo due at 09:00 Europe/Berlin stays at 09:00 across DST changes.
+package rrule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxSearchDays bounds the search for the next occurrence per unit of
+// INTERVAL, so rules that never match, such as BYMONTH=2;BYMONTHDAY=30,
+// end instead of looping forever. Eight years covers the longest gap between
+// leap days.
+const maxSearchDays = 8 * 366
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// WeekdayNum is a BYDAY entry. N is the ordinal within the month, e.g. 1 for
+// the first or -1 for the last; 0 means every such weekday.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE". An
+// "RRULE:" prefix is accepted.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("empty rule")
+	}
+
+	rule := &Rule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s given more than once", name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			rule.Freq = Frequency(value)
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly && rule.Freq != Yearly {
+				err = fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = parsePositive(name, value)
+		case "COUNT":
+			rule.Count, err = parsePositive(name, value)
+		case "UNTIL":
+			rule.Until, err = parseUntil(value)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseIntList(name, value, 1, 31, true)
+		case "BYMONTH":
+			var months []int
+			months, err = parseIntList(name, value, 1, 12, false)
+			for _, month := range months {
+				rule.ByMonth = append(rule.ByMonth, time.Month(month))
+			}
+		default:
+			err = fmt.Errorf("unsupported rule part %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot be combined")
+	}
+	if rule.Freq == Weekly && len(rule.ByMonthDay) > 0 {
+		return nil, fmt.Errorf("BYMONTHDAY cannot be used with FREQ=WEEKLY")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly && !(rule.Freq == Yearly && len(rule.ByMonth) > 0) {
+			return nil, fmt.Errorf("numbered BYDAY needs FREQ=MONTHLY, or FREQ=YEARLY with BYMONTH")
+		}
+	}
+
+	return rule, nil
+}
+
+// String returns the rule in canonical form, without the "RRULE:" prefix.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = formatWeekday(day)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, month := range r.ByMonth {
+			months[i] = strconv.Itoa(int(month))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the occurrence following current, which is occurrence number
+// index (starting at 1) of the series. current's location decides the
+// calendar days and wall-clock time. ok is false when the series has ended.
+func (r *Rule) Next(current time.Time, index int) (next time.Time, ok bool) {
+	if r.Count > 0 && index >= r.Count {
+		return time.Time{}, false
+	}
+
+	limit := maxSearchDays * r.Interval
+	for offset := 1; offset <= limit; offset++ {
+		// time.Date keeps the wall-clock time; one falling in a DST gap is
+		// moved forward by the length of the gap
+		day := time.Date(current.Year(), current.Month(), current.Day()+offset,
+			current.Hour(), current.Minute(), current.Second(), 0, current.Location())
+		if !r.inPeriod(current, day) || !r.matches(current, day) {
+			continue
+		}
+		if !r.Until.IsZero() && day.After(r.Until) {
+			return time.Time{}, false
+		}
+		return day, true
+	}
+
+	return time.Time{}, false
+}
+
+// Occurrences returns up to n occurrences starting with first, which is
+// occurrence number index of the series.
+func (r *Rule) Occurrences(first time.Time, index, n int) []time.Time {
+	var occurrences []time.Time
+	if n <= 0 || (!r.Until.IsZero() && first.After(r.Until)) {
+		return occurrences
+	}
+
+	current := first
+	occurrences = append(occurrences, current)
+	for len(occurrences) < n {
+		next, ok := r.Next(current, index)
+		if !ok {
+			break
+		}
+		occurrences = append(occurrences, next)
+		current = next
+		index++
+	}
+	return occurrences
+}
+
+// inPeriod reports whether day falls in a period the interval selects,
+// counting periods from the one holding current.
+func (r *Rule) inPeriod(current, day time.Time) bool {
+	var periods int
+	switch r.Freq {
+	case Daily:
+		periods = civilDay(day) - civilDay(current)
+	case Weekly:
+		periods = (weekStart(day) - weekStart(current)) / 7
+	case Monthly:
+		periods = (day.Year()-current.Year())*12 + int(day.Month()) - int(current.Month())
+	case Yearly:
+		periods = day.Year() - current.Year()
+	}
+	return periods%r.Interval == 0
+}
+
+// matches applies the BY* parts. Without them a rule repeats on the weekday,
+// day of month or date of current, depending on its frequency.
+func (r *Rule) matches(current, day time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsMonth(r.ByMonth, day.Month()) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 && !r.matchesMonthDay(day) {
+		return false
+	}
+	if len(r.ByDay) > 0 && !r.matchesWeekday(day) {
+		return false
+	}
+
+	switch r.Freq {
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			return day.Weekday() == current.Weekday()
+		}
+	case Monthly:
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			return day.Day() == current.Day()
+		}
+	case Yearly:
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			if len(r.ByMonth) == 0 && day.Month() != current.Month() {
+				return false
+			}
+			return day.Day() == current.Day()
+		}
+	}
+	return true
+}
+
+func (r *Rule) matchesMonthDay(day time.Time) bool {
+	last := daysInMonth(day)
+	for _, monthDay := range r.ByMonthDay {
+		if monthDay == day.Day() || (monthDay < 0 && last+monthDay+1 == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesWeekday(day time.Time) bool {
+	for _, weekday := range r.ByDay {
+		if weekday.Day != day.Weekday() {
+			continue
+		}
+		switch {
+		case weekday.N == 0:
+			return true
+		case weekday.N > 0 && (day.Day()-1)/7+1 == weekday.N:
+			return true
+		case weekday.N < 0 && (daysInMonth(day)-day.Day())/7+1 == -weekday.N:
+			return true
+		}
+	}
+	return false
+}
+
+func parsePositive(name, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive number", name)
+	}
+	return n, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// A date includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("UNTIL must be a UTC date-time such as 20261231T235959Z or a date such as 20261231")
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		weekday, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("invalid BYDAY %q", item)
+			}
+		}
+		days = append(days, WeekdayNum{N: n, Day: weekday})
+	}
+	return days, nil
+}
+
+func parseIntList(name, value string, min, max int, allowNegative bool) ([]int, error) {
+	var numbers []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(item)
+		abs := n
+		if abs < 0 && allowNegative {
+			abs = -abs
+		}
+		if err != nil || abs < min || abs > max {
+			return nil, fmt.Errorf("invalid %s %q", name, item)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+func formatWeekday(day WeekdayNum) string {
+	for name, weekday := range weekdays {
+		if weekday == day.Day {
+			if day.N != 0 {
+				return strconv.Itoa(day.N) + name
+			}
+			return name
+		}
+	}
+	return ""
+}
+
+func containsMonth(months []time.Month, month time.Month) bool {
+	for _, m := range months {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+// civilDay numbers calendar days, ignoring time of day and location.
+func civilDay(t time.Time) int {
+	return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// weekStart returns the civil day of the Monday starting t's week.
+func weekStart(t time.Time) int {
+	return civilDay(t) - (int(t.Weekday())+6)%7
+}
+
+func daysInMonth(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,179 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{"daily", "FREQ=DAILY", "FREQ=DAILY", false},
+		{"prefix and lower case", "rrule:freq=weekly;byday=mo,we;interval=2", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", false},
+		{"interval of one is dropped", "FREQ=DAILY;INTERVAL=1", "FREQ=DAILY", false},
+		{"until date covers the day", "FREQ=DAILY;UNTIL=20261231", "FREQ=DAILY;UNTIL=20261231T235959Z", false},
+		{"until date-time", "FREQ=DAILY;UNTIL=20261231T120000Z", "FREQ=DAILY;UNTIL=20261231T120000Z", false},
+		{"numbered weekday", "FREQ=MONTHLY;BYDAY=-1FR", "FREQ=MONTHLY;BYDAY=-1FR", false},
+		{"numbered weekday in yearly with month", "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", "FREQ=YEARLY;BYDAY=4TH;BYMONTH=11", false},
+		{"month days and months", "FREQ=YEARLY;BYMONTH=1,7;BYMONTHDAY=1,-1", "FREQ=YEARLY;BYMONTHDAY=1,-1;BYMONTH=1,7", false},
+		{"count", "FREQ=WEEKLY;COUNT=5", "FREQ=WEEKLY;COUNT=5", false},
+
+		{"empty", "", "", true},
+		{"prefix only", "RRULE:", "", true},
+		{"missing freq", "INTERVAL=2", "", true},
+		{"unsupported freq", "FREQ=HOURLY", "", true},
+		{"part without value", "FREQ", "", true},
+		{"repeated part", "FREQ=DAILY;FREQ=WEEKLY", "", true},
+		{"unsupported part", "FREQ=DAILY;WKST=MO", "", true},
+		{"zero interval", "FREQ=DAILY;INTERVAL=0", "", true},
+		{"zero count", "FREQ=DAILY;COUNT=0", "", true},
+		{"count and until", "FREQ=DAILY;COUNT=2;UNTIL=20261231", "", true},
+		{"invalid until", "FREQ=DAILY;UNTIL=tomorrow", "", true},
+		{"invalid weekday", "FREQ=WEEKLY;BYDAY=XX", "", true},
+		{"weekday ordinal out of range", "FREQ=MONTHLY;BYDAY=6MO", "", true},
+		{"zero weekday ordinal", "FREQ=MONTHLY;BYDAY=0MO", "", true},
+		{"numbered weekday in weekly", "FREQ=WEEKLY;BYDAY=1MO", "", true},
+		{"numbered weekday in yearly without month", "FREQ=YEARLY;BYDAY=1MO", "", true},
+		{"month day out of range", "FREQ=MONTHLY;BYMONTHDAY=32", "", true},
+		{"zero month day", "FREQ=MONTHLY;BYMONTHDAY=0", "", true},
+		{"month day in weekly", "FREQ=WEEKLY;BYMONTHDAY=1", "", true},
+		{"negative month", "FREQ=YEARLY;BYMONTH=-1", "", true},
+		{"month out of range", "FREQ=YEARLY;BYMONTH=13", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse(%q) = %q, want an error", tt.rule, rule)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("Parse(%q).String() = %q, want %q", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	// 1 January 2026 is a Thursday
+	tests := []struct {
+		name  string
+		rule  string
+		first time.Time
+		n     int
+		want  []time.Time
+	}{
+		{"daily", "FREQ=DAILY", date(2026, 1, 30), 3,
+			[]time.Time{date(2026, 1, 30), date(2026, 1, 31), date(2026, 2, 1)}},
+		{"every other day", "FREQ=DAILY;INTERVAL=2", date(2026, 1, 1), 3,
+			[]time.Time{date(2026, 1, 1), date(2026, 1, 3), date(2026, 1, 5)}},
+		{"weekly on the first weekday", "FREQ=WEEKLY", date(2026, 1, 1), 3,
+			[]time.Time{date(2026, 1, 1), date(2026, 1, 8), date(2026, 1, 15)}},
+		{"weekly on several days", "FREQ=WEEKLY;BYDAY=MO,WE", date(2026, 1, 5), 4,
+			[]time.Time{date(2026, 1, 5), date(2026, 1, 7), date(2026, 1, 12), date(2026, 1, 14)}},
+		{"every other week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", date(2026, 1, 5), 4,
+			[]time.Time{date(2026, 1, 5), date(2026, 1, 7), date(2026, 1, 19), date(2026, 1, 21)}},
+		{"weekly interval counts from the first week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", date(2026, 1, 7), 3,
+			[]time.Time{date(2026, 1, 7), date(2026, 1, 19), date(2026, 2, 2)}},
+		{"monthly skips short months", "FREQ=MONTHLY", date(2026, 1, 31), 3,
+			[]time.Time{date(2026, 1, 31), date(2026, 3, 31), date(2026, 5, 31)}},
+		{"last day of the month", "FREQ=MONTHLY;BYMONTHDAY=-1", date(2026, 1, 31), 3,
+			[]time.Time{date(2026, 1, 31), date(2026, 2, 28), date(2026, 3, 31)}},
+		{"second tuesday", "FREQ=MONTHLY;BYDAY=2TU", date(2026, 1, 13), 3,
+			[]time.Time{date(2026, 1, 13), date(2026, 2, 10), date(2026, 3, 10)}},
+		{"last friday", "FREQ=MONTHLY;BYDAY=-1FR", date(2026, 1, 30), 3,
+			[]time.Time{date(2026, 1, 30), date(2026, 2, 27), date(2026, 3, 27)}},
+		{"quarterly", "FREQ=MONTHLY;INTERVAL=3", date(2026, 1, 15), 3,
+			[]time.Time{date(2026, 1, 15), date(2026, 4, 15), date(2026, 7, 15)}},
+		{"yearly on a leap day", "FREQ=YEARLY", date(2024, 2, 29), 2,
+			[]time.Time{date(2024, 2, 29), date(2028, 2, 29)}},
+		{"yearly in several months", "FREQ=YEARLY;BYMONTH=1,7", date(2026, 1, 15), 3,
+			[]time.Time{date(2026, 1, 15), date(2026, 7, 15), date(2027, 1, 15)}},
+		{"fourth thursday of november", "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", date(2026, 11, 26), 2,
+			[]time.Time{date(2026, 11, 26), date(2027, 11, 25)}},
+		{"count", "FREQ=DAILY;COUNT=3", date(2026, 1, 1), 10,
+			[]time.Time{date(2026, 1, 1), date(2026, 1, 2), date(2026, 1, 3)}},
+		{"until includes the whole day", "FREQ=DAILY;UNTIL=20260103", date(2026, 1, 1), 10,
+			[]time.Time{date(2026, 1, 1), date(2026, 1, 2), date(2026, 1, 3)}},
+		{"until before the first", "FREQ=DAILY;UNTIL=20251231", date(2026, 1, 1), 10, nil},
+		{"rule that never matches again", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", date(2026, 1, 1), 3,
+			[]time.Time{date(2026, 1, 1)}},
+		{"no occurrences requested", "FREQ=DAILY", date(2026, 1, 1), 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := rule.Occurrences(tt.first, 1, tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Occurrences() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i+1, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNextContinuesCount(t *testing.T) {
+	rule, err := Parse("FREQ=DAILY;COUNT=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rule.Next(date(2026, 1, 2), 2); !ok {
+		t.Error("Next after occurrence 2 of 3 ended the series")
+	}
+	if next, ok := rule.Next(date(2026, 1, 3), 3); ok {
+		t.Errorf("Next after occurrence 3 of 3 = %v, want the series to end", next)
+	}
+}
+
+func TestOccurrencesKeepWallClockAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data not available")
+	}
+	rule, err := Parse("FREQ=DAILY")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Summer time starts on 29 March 2026
+	first := time.Date(2026, 3, 28, 9, 0, 0, 0, berlin)
+	got := rule.Occurrences(first, 1, 2)
+	if len(got) != 2 {
+		t.Fatalf("Occurrences() = %v", got)
+	}
+	if got[1].Hour() != 9 || got[1].Day() != 29 {
+		t.Errorf("occurrence after the change = %v, want 09:00 on 29 March", got[1])
+	}
+	if gap := got[1].Sub(got[0]); gap != 23*time.Hour {
+		t.Errorf("gap across the change = %v, want 23h", gap)
+	}
+
+	// 02:30 does not exist on 29 March and moves forward by the gap
+	first = time.Date(2026, 3, 28, 2, 30, 0, 0, berlin)
+	got = rule.Occurrences(first, 1, 2)
+	if got[1].Hour() != 3 || got[1].Minute() != 30 {
+		t.Errorf("occurrence in the gap = %v, want 03:30", got[1])
+	}
+}
@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+const (
+	apiKeyPrefix = "tdk"
+	// lastUsedResolution limits last_used_at writes to one per key per minute
+	lastUsedResolution = time.Minute
+)
+
+type APIKeyUseCase interface {
+	Create(userID int, req *model.CreateAPIKeyRequest) (*model.CreateAPIKeyResponse, error)
+	GetByUserID(userID int) ([]*entity.APIKey, error)
+	Revoke(userID, keyID int) error
+	Authenticate(key string) (*entity.User, *entity.APIKey, error)
+}
+
+type apiKeyUseCase struct {
+	apiKeyRepo repository.APIKeyRepository
+	userRepo   repository.UserRepository
+}
+
+func NewAPIKeyUseCase(apiKeyRepo repository.APIKeyRepository, userRepo repository.UserRepository) APIKeyUseCase {
+	return &apiKeyUseCase{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+	}
+}
+
+func (uc *apiKeyUseCase) Create(userID int, req *model.CreateAPIKeyRequest) (*model.CreateAPIKeyResponse, error) {
+	validationErr := &ValidationError{}
+	if strings.TrimSpace(req.Name) == "" {
+		validationErr.Add("name", "must not be empty")
+	}
+	if len(req.Scopes) == 0 {
+		validationErr.Add("scopes", "at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !entity.IsKnownPermission(scope) {
+			validationErr.Add("scopes", fmt.Sprintf("unknown permission %q", scope))
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		validationErr.Add("expires_at", "must be in the future")
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	prefix, secret, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + "_" + prefix + "_" + secret
+
+	apiKey, err := uc.apiKeyRepo.Create(&entity.APIKey{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    prefix,
+		KeyHash:   hashAPIKey(key),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return &model.CreateAPIKeyResponse{
+		Key:    key,
+		APIKey: apiKey,
+	}, nil
+}
+
+func (uc *apiKeyUseCase) GetByUserID(userID int) ([]*entity.APIKey, error) {
+	keys, err := uc.apiKeyRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (uc *apiKeyUseCase) Revoke(userID, keyID int) error {
+	if err := uc.apiKeyRepo.Revoke(keyID, userID); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return nil
+}
+
+// Authenticate resolves a plain API key to its owner. Every failure returns
+// the same error so callers cannot tell unknown keys from revoked ones.
+func (uc *apiKeyUseCase) Authenticate(key string) (*entity.User, *entity.APIKey, error) {
+	invalid := fmt.Errorf("invalid api key")
+
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return nil, nil, invalid
+	}
+
+	apiKey, err := uc.apiKeyRepo.GetByPrefix(parts[1])
+	if err != nil {
+		return nil, nil, invalid
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(hashAPIKey(key))) != 1 {
+		return nil, nil, invalid
+	}
+
+	now := time.Now()
+	if !apiKey.IsActive(now) {
+		return nil, nil, invalid
+	}
+
+	user, err := uc.userRepo.GetByID(apiKey.UserID)
+	if err != nil || user.IsDisabled() {
+		return nil, nil, invalid
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedResolution {
+		// Failing to record usage must not fail the request
+		if err := uc.apiKeyRepo.TouchLastUsed(apiKey.ID, now); err != nil {
+			log.Printf("failed to update last used time of api key %d: %v", apiKey.ID, err)
+		}
+	}
+
+	user.Password = ""
+	return user, apiKey, nil
+}
+
+func generateAPIKey() (string, string, error) {
+	prefix := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	// The secret must not contain the "_" separator, so use hex
+	return hex.EncodeToString(prefix), hex.EncodeToString(secret), nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+const (
+	defaultAuditLogLimit = 100
+	maxAuditLogLimit     = 1000
+)
+
+type AuditUseCase interface {
+	Record(entry *entity.AuditLog) error
+	GetRecent(limit int) ([]*entity.AuditLog, error)
+}
+
+type auditUseCase struct {
+	auditRepo repository.AuditLogRepository
+}
+
+func NewAuditUseCase(auditRepo repository.AuditLogRepository) AuditUseCase {
+	return &auditUseCase{
+		auditRepo: auditRepo,
+	}
+}
+
+func (uc *auditUseCase) Record(entry *entity.AuditLog) error {
+	if _, err := uc.auditRepo.Create(entry); err != nil {
+		return fmt.Errorf("failed to record audit log: %w", err)
+	}
+	return nil
+}
+
+// GetRecent returns the newest entries first. A non-positive limit uses the
+// default; larger limits are capped.
+func (uc *auditUseCase) GetRecent(limit int) ([]*entity.AuditLog, error) {
+	if limit <= 0 {
+		limit = defaultAuditLogLimit
+	}
+	if limit > maxAuditLogLimit {
+		limit = maxAuditLogLimit
+	}
+
+	entries, err := uc.auditRepo.GetRecent(limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit logs: %w", err)
+	}
+
+	return entries, nil
+}
@@ -1,61 +1,309 @@
 package usecase
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 
 	"github.com/islamyakin/otel-propagation-monorepo/internal/config"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/hasher"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
 )
 
 type AuthUseCase interface {
+	// Register returns a nil user without error when generic registration
+	// responses are enabled, whether or not the account was created.
 	Register(req *model.RegisterRequest) (*entity.User, error)
 	Login(req *model.LoginRequest) (*model.LoginResponse, error)
 	VerifyToken(tokenString string) (*JWTClaims, error)
+	CreateAdmin(username, password string) (*entity.User, error)
+	BootstrapAdmin() (*entity.User, error)
+	LoginWithMFA(req *model.MFALoginRequest) (*model.LoginResponse, error)
+	EnrollMFAForLogin(req *model.MFAEnrollRequest) (*model.MFAEnrollResponse, error)
+	ConfirmMFAForLogin(req *model.MFAConfirmLoginRequest) (*model.LoginResponse, error)
+	Impersonate(actor *entity.Actor, targetUserID int, req *model.ImpersonateRequest) (*model.ImpersonateResponse, error)
+	ExchangeToken(req *model.TokenExchangeRequest) (*model.TokenExchangeResponse, error)
 }
 
+// tokenPurposeMFAChallenge marks the short-lived token returned by Login when a
+// second factor is still needed. It is rejected by VerifyToken.
+const tokenPurposeMFAChallenge = "mfa_challenge"
+
+const accessTokenTTL = 24 * time.Hour
+
+// RFC 8693 identifiers accepted by ExchangeToken
+const (
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+	tokenTypeJWT           = "urn:ietf:params:oauth:token-type:jwt"
+)
+
 type JWTClaims struct {
 	UserID   int         `json:"user_id"`
 	Username string      `json:"username"`
 	Role     entity.Role `json:"role"`
+	// TokenVersion must match the user's current version for the token to be accepted
+	TokenVersion int `json:"ver"`
+	// Purpose is empty for access tokens
+	Purpose string `json:"purpose,omitempty"`
+	// Act names the admin when the token was minted for impersonation
+	Act *ActClaim `json:"act,omitempty"`
+	// SessionID links the token to the login it was issued for
+	SessionID int `json:"sid,omitempty"`
+	// Scope limits an exchanged token to a space-separated list of
+	// permissions. It is empty for tokens holding all of the role's
+	// permissions.
+	Scope string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Scopes returns the permissions the token is limited to, or nil when it is
+// not limited.
+func (c *JWTClaims) Scopes() entity.PermissionSet {
+	if c.Scope == "" {
+		return nil
+	}
+	return parseScope(c.Scope)
+}
+
+// tokenOptions holds the claims that differ between the kinds of token.
+type tokenOptions struct {
+	purpose   string
+	act       *ActClaim
+	sessionID int
+	// audience overrides JWT_AUDIENCE
+	audience string
+	scope    string
+}
+
+// ActClaim is the RFC 8693 actor claim: the party acting on behalf of the
+// token's subject.
+type ActClaim struct {
+	Subject  string `json:"sub"`
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+}
+
 type authUseCase struct {
-	userRepo repository.UserRepository
-	config   *config.Config
+	userRepo       repository.UserRepository
+	users          *UserCache
+	sessionRepo    repository.SessionRepository
+	mfaUseCase     MFAUseCase
+	auditUseCase   AuditUseCase
+	emailUseCase   EmailUseCase
+	roleUseCase    RoleUseCase
+	policy         CredentialPolicy
+	passwordHasher hasher.Hasher
+	throttle       *loginThrottle
+	config         *config.Config
+	// dummyHash is compared against for unknown usernames
+	dummyHash *dummyHash
 }
 
-func NewAuthUseCase(userRepo repository.UserRepository, config *config.Config) AuthUseCase {
+func NewAuthUseCase(
+	userRepo repository.UserRepository,
+	attemptRepo repository.LoginAttemptRepository,
+	sessionRepo repository.SessionRepository,
+	mfaUseCase MFAUseCase,
+	auditUseCase AuditUseCase,
+	emailUseCase EmailUseCase,
+	roleUseCase RoleUseCase,
+	policy CredentialPolicy,
+	passwordHasher hasher.Hasher,
+	users *UserCache,
+	config *config.Config,
+) AuthUseCase {
 	return &authUseCase{
-		userRepo: userRepo,
-		config:   config,
+		userRepo:       userRepo,
+		users:          users,
+		sessionRepo:    sessionRepo,
+		mfaUseCase:     mfaUseCase,
+		auditUseCase:   auditUseCase,
+		emailUseCase:   emailUseCase,
+		roleUseCase:    roleUseCase,
+		policy:         policy,
+		passwordHasher: passwordHasher,
+		throttle:       newLoginThrottle(attemptRepo, config.Login),
+		config:         config,
+		dummyHash:      &dummyHash{userRepo: userRepo, passwordHasher: passwordHasher},
 	}
 }
 
+// dummyHashRefreshInterval is how often the dummy hash follows the stored
+// hashes as logins upgrade them to the current settings.
+const dummyHashRefreshInterval = time.Hour
+
+// dummyHash is a hash of a random password using the algorithm and
+// parameters most stored hashes use, so that checking a password against it
+// costs as much as checking most real ones and never succeeds. Accounts whose
+// hash uses a less common profile, such as legacy bcrypt hashes after
+// switching to argon2id, still take a different time until their owners log
+// in and the hash is upgraded.
+type dummyHash struct {
+	userRepo       repository.UserRepository
+	passwordHasher hasher.Hasher
+
+	mu          sync.Mutex
+	hash        string
+	refreshedAt time.Time
+	refreshing  bool
+}
+
+// Get returns the current dummy hash. Only the first call waits for it to be
+// made; later refreshes run in the background so they do not slow down the
+// login that notices the hash is due.
+func (d *dummyHash) Get() string {
+	d.mu.Lock()
+	hash, due := d.hash, time.Since(d.refreshedAt) >= dummyHashRefreshInterval
+	if hash != "" && due && !d.refreshing {
+		d.refreshing = true
+		go d.refresh()
+	}
+	d.mu.Unlock()
+
+	if hash == "" {
+		d.refresh()
+		d.mu.Lock()
+		hash = d.hash
+		d.mu.Unlock()
+	}
+	return hash
+}
+
+func (d *dummyHash) refresh() {
+	defer func() {
+		d.mu.Lock()
+		d.refreshing = false
+		d.mu.Unlock()
+	}()
+
+	// Without a reference the configured settings are used
+	reference, err := d.userRepo.MostCommonPasswordHash()
+	if err != nil {
+		log.Printf("failed to get reference for dummy password hash: %v", err)
+	}
+
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		log.Printf("failed to generate dummy password: %v", err)
+		return
+	}
+
+	hash, err := d.passwordHasher.HashLike(reference, hex.EncodeToString(password))
+	if err != nil {
+		log.Printf("failed to hash dummy password: %v", err)
+		return
+	}
+
+	d.mu.Lock()
+	d.hash = hash
+	d.refreshedAt = time.Now()
+	d.mu.Unlock()
+}
+
 func (uc *authUseCase) Register(req *model.RegisterRequest) (*entity.User, error) {
-	// Check if user already exists
-	existingUser, err := uc.userRepo.GetByUsername(req.Username)
+	// Create user with default role "user"
+	user, err := uc.createUser(req.Username, req.Password, req.Email, entity.UserRole)
+	if err == nil && user.Email != "" {
+		// Sent in the background so the delivery time does not show which
+		// registrations created an account. The account exists either way;
+		// the user can ask for another link.
+		go func(userID int) {
+			if err := uc.emailUseCase.SendVerification(userID); err != nil {
+				log.Printf("failed to send verification email to user %d: %v", userID, err)
+			}
+		}(user.ID)
+	}
+	if !uc.config.Auth.GenericRegistrationResponse {
+		return user, err
+	}
+
+	// Validation errors say nothing about other accounts and are still reported
+	switch {
+	case isDuplicateAccount(err):
+		// Spend the time a successful registration would spend hashing
+		_, _ = uc.passwordHasher.Hash(req.Password)
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (uc *authUseCase) CreateAdmin(username, password string) (*entity.User, error) {
+	return uc.createUser(username, password, "", entity.AdminRole)
+}
+
+// BootstrapAdmin creates the admin configured through ADMIN_BOOTSTRAP_USERNAME
+// and ADMIN_BOOTSTRAP_PASSWORD. It does nothing and returns a nil user when no
+// credentials are configured or an admin already exists, so it is safe to run
+// on every startup.
+func (uc *authUseCase) BootstrapAdmin() (*entity.User, error) {
+	username := uc.config.Admin.BootstrapUsername
+	password := uc.config.Admin.BootstrapPassword
+	if username == "" || password == "" {
+		return nil, nil
+	}
+
+	count, err := uc.userRepo.CountByRole(entity.AdminRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing admin: %w", err)
+	}
+	if count > 0 {
+		return nil, nil
+	}
+
+	user, err := uc.CreateAdmin(username, password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bootstrap admin: %w", err)
+	}
+
+	return user, nil
+}
+
+func (uc *authUseCase) createUser(username, password, email string, role entity.Role) (*entity.User, error) {
+	email = normalizeEmail(email)
+	if err := validateCredentials(uc.policy, "username", username, "password", password); err != nil {
+		return nil, err
+	}
+	if email != "" {
+		validationErr := &ValidationError{}
+		validateEmail(validationErr, "email", email)
+		if err := validationErr.ErrOrNil(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check if user already exists (usernames are compared case-insensitively)
+	existingUser, err := uc.userRepo.GetByUsername(username)
 	if err == nil && existingUser != nil {
-		return nil, fmt.Errorf("user already exists")
+		return nil, ErrUserExists
+	}
+	if email != "" {
+		if _, err := uc.userRepo.GetByEmail(email); err == nil {
+			return nil, ErrEmailTaken
+		}
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := uc.passwordHasher.Hash(password)
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash password: %w", err)
+		return nil, err
 	}
 
-	// Create user with default role "user"
 	user := &entity.User{
-		Username: req.Username,
-		Password: string(hashedPassword),
-		Role:     entity.UserRole,
+		Username: username,
+		Password: hashedPassword,
+		Role:     role,
+		Email:    email,
 	}
 
 	createdUser, err := uc.userRepo.Create(user)
@@ -69,20 +317,343 @@ func (uc *authUseCase) Register(req *model.RegisterRequest) (*entity.User, error
 }
 
 func (uc *authUseCase) Login(req *model.LoginRequest) (*model.LoginResponse, error) {
-	// Get user by username
-	user, err := uc.userRepo.GetByUsername(req.Username)
-	if err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+	// Get user by username or email. Unknown users get the same hashing work
+	// as known ones so the response time does not reveal which accounts exist.
+	user, lookupErr := findUserByLogin(uc.userRepo, req.Username)
+	account := accountThrottleKey(user, req.Username)
+
+	// Refuse early while the account or client is locked out
+	if err := uc.throttle.Check(account, req.ClientIP); err != nil {
+		return nil, err
+	}
+
+	if lookupErr != nil {
+		_, _ = uc.passwordHasher.Verify(uc.dummyHash.Get(), req.Password)
+		return nil, uc.loginFailed(account, req.ClientIP)
 	}
 
 	// Verify password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	if ok, err := uc.passwordHasher.Verify(user.Password, req.Password); !ok {
+		if err != nil {
+			log.Printf("failed to verify password of user %d: %v", user.ID, err)
+		}
+		return nil, uc.loginFailed(account, req.ClientIP)
+	}
+
+	if user.IsDisabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	uc.upgradePasswordHash(user, req.Password)
+
+	// A second factor is needed when enrolled, or required for the role
+	mfaEnabled, err := uc.mfaUseCase.IsEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	enrollmentRequired := !mfaEnabled && uc.config.MFA.RequireForAdmin && user.Role == entity.AdminRole
+	if mfaEnabled || enrollmentRequired {
+		mfaToken, err := uc.generateChallengeToken(user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate mfa token: %w", err)
+		}
+
+		return &model.LoginResponse{
+			MFARequired:           true,
+			MFAEnrollmentRequired: enrollmentRequired,
+			MFAToken:              mfaToken,
+		}, nil
+	}
+
+	return uc.completeLogin(user, req.ClientIP, req.UserAgent)
+}
+
+// upgradePasswordHash rehashes the password with the current algorithm and
+// parameters when the stored hash is older. It only logs failures; the user
+// has already proven the password. Only the hash is written, and only if it
+// is still the one verified, so changes made to the user during the slow
+// verification are kept.
+func (uc *authUseCase) upgradePasswordHash(user *entity.User, password string) {
+	if !uc.passwordHasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hashedPassword, err := uc.passwordHasher.Hash(password)
+	if err != nil {
+		log.Printf("failed to rehash password of user %d: %v", user.ID, err)
+		return
+	}
+
+	replaced, err := uc.userRepo.ReplacePasswordHash(user.ID, user.Password, hashedPassword)
+	if err != nil {
+		log.Printf("failed to store rehashed password of user %d: %v", user.ID, err)
+		return
+	}
+	if replaced {
+		user.Password = hashedPassword
+	}
+}
+
+func (uc *authUseCase) LoginWithMFA(req *model.MFALoginRequest) (*model.LoginResponse, error) {
+	user, err := uc.verifyChallengeToken(req.MFAToken)
 	if err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, err
+	}
+
+	if err := uc.throttle.Check(accountThrottleKey(user, ""), req.ClientIP); err != nil {
+		return nil, err
+	}
+
+	if err := uc.mfaUseCase.Verify(user.ID, req.Code, req.RecoveryCode); err != nil {
+		if failErr := uc.throttle.RecordFailure(accountThrottleKey(user, ""), req.ClientIP); failErr != nil {
+			return nil, failErr
+		}
+		return nil, err
+	}
+
+	return uc.completeLogin(user, req.ClientIP, req.UserAgent)
+}
+
+func (uc *authUseCase) EnrollMFAForLogin(req *model.MFAEnrollRequest) (*model.MFAEnrollResponse, error) {
+	user, err := uc.verifyChallengeToken(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.mfaUseCase.Enroll(user.ID)
+}
+
+// ConfirmMFAForLogin finishes an enrollment that was forced at login and
+// signs the user in, returning the recovery codes alongside the token.
+func (uc *authUseCase) ConfirmMFAForLogin(req *model.MFAConfirmLoginRequest) (*model.LoginResponse, error) {
+	user, err := uc.verifyChallengeToken(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.throttle.Check(accountThrottleKey(user, ""), req.ClientIP); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := uc.mfaUseCase.Confirm(user.ID, req.Code)
+	if err != nil {
+		if failErr := uc.throttle.RecordFailure(accountThrottleKey(user, ""), req.ClientIP); failErr != nil {
+			return nil, failErr
+		}
+		return nil, err
+	}
+
+	response, err := uc.completeLogin(user, req.ClientIP, req.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	response.RecoveryCodes = recoveryCodes
+	return response, nil
+}
+
+// Impersonate mints a short-lived token for the target user that carries the
+// admin in its act claim. Admins cannot be impersonated and impersonation
+// tokens cannot be used to impersonate again.
+func (uc *authUseCase) Impersonate(actor *entity.Actor, targetUserID int, req *model.ImpersonateRequest) (*model.ImpersonateResponse, error) {
+	if !actor.Can(entity.PermissionUsersImpersonate) {
+		return nil, &ForbiddenError{Permission: entity.PermissionUsersImpersonate}
+	}
+	if actor.IsImpersonated() {
+		return nil, fmt.Errorf("cannot impersonate while impersonating")
+	}
+	if targetUserID == actor.UserID {
+		return nil, fmt.Errorf("cannot impersonate yourself")
+	}
+
+	admin, err := uc.userRepo.GetByID(actor.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	target, err := uc.userRepo.GetByID(targetUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if target.Role == entity.AdminRole {
+		return nil, fmt.Errorf("admins cannot be impersonated")
+	}
+	if target.IsDisabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	// The token carries the target's permissions, so impersonating must not
+	// grant anything the actor does not already hold
+	granted, err := uc.roleUseCase.PermissionsFor(target.Role)
+	if err != nil {
+		return nil, err
+	}
+	for _, permission := range granted.List() {
+		if !actor.Can(permission) {
+			return nil, &ForbiddenError{Permission: permission}
+		}
+	}
+
+	// Record before issuing so no token exists without an audit entry
+	err = uc.auditUseCase.Record(&entity.AuditLog{
+		ActorUserID:  admin.ID,
+		Action:       entity.AuditActionImpersonationStart,
+		TargetUserID: target.ID,
+		Details:      req.Reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := uc.config.Admin.ImpersonationTTL
+	act := &ActClaim{
+		Subject:  fmt.Sprintf("%d", admin.ID),
+		UserID:   admin.ID,
+		Username: admin.Username,
+	}
+	token, err := uc.signToken(target, tokenOptions{act: act}, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	// Remove password from response
+	target.Password = ""
+
+	return &model.ImpersonateResponse{
+		Token:     token,
+		ExpiresAt: time.Now().Add(ttl),
+		User:      *target,
+	}, nil
+}
+
+// ExchangeToken trades a valid access token for one limited to audience and
+// a subset of the subject token's permissions. The new token lives at most
+// TOKEN_EXCHANGE_TTL and never outlives the subject token. It stays tied to
+// the same session and impersonation, so revoking either revokes it too.
+func (uc *authUseCase) ExchangeToken(req *model.TokenExchangeRequest) (*model.TokenExchangeResponse, error) {
+	validationErr := &ValidationError{}
+	if req.GrantType != grantTypeTokenExchange {
+		validationErr.Add("grant_type", "must be "+grantTypeTokenExchange)
+	}
+	if req.SubjectTokenType != tokenTypeAccessToken && req.SubjectTokenType != tokenTypeJWT {
+		validationErr.Add("subject_token_type", "must be "+tokenTypeAccessToken+" or "+tokenTypeJWT)
+	}
+	if !uc.isExchangeAudience(req.Audience) {
+		validationErr.Add("audience", fmt.Sprintf("audience %q is not allowed", req.Audience))
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	claims, err := uc.VerifyToken(req.SubjectToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject token")
+	}
+
+	granted, err := uc.roleUseCase.PermissionsFor(claims.Role)
+	if err != nil {
+		return nil, err
+	}
+	if scopes := claims.Scopes(); scopes != nil {
+		granted = granted.Intersect(scopes)
+	}
+
+	requested := parseScope(req.Scope)
+	if len(requested) == 0 {
+		validationErr.Add("scope", "must list at least one permission")
+	}
+	for _, permission := range requested.List() {
+		if !granted.Has(permission) {
+			validationErr.Add("scope", fmt.Sprintf("permission %q is not held by the subject token", permission))
+		}
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	ttl := uc.config.JWT.ExchangeTTL
+	if claims.ExpiresAt != nil {
+		if remaining := time.Until(claims.ExpiresAt.Time); remaining < ttl {
+			ttl = remaining
+		}
+	}
+
+	user := &entity.User{
+		ID:           claims.UserID,
+		Username:     claims.Username,
+		Role:         claims.Role,
+		TokenVersion: claims.TokenVersion,
+	}
+	scope := formatScope(requested)
+	token, err := uc.signToken(user, tokenOptions{
+		act:       claims.Act,
+		sessionID: claims.SessionID,
+		audience:  req.Audience,
+		scope:     scope,
+	}, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &model.TokenExchangeResponse{
+		AccessToken:     token,
+		IssuedTokenType: tokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int(ttl.Seconds()),
+		Scope:           scope,
+	}, nil
+}
+
+func (uc *authUseCase) isExchangeAudience(audience string) bool {
+	if audience == "" {
+		return false
+	}
+	if audience == uc.config.JWT.Audience {
+		return true
+	}
+	for _, allowed := range uc.config.JWT.ExchangeAudiences {
+		if audience == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// parseScope reads a space-separated scope. Unknown permissions are kept so
+// callers can report them.
+func parseScope(scope string) entity.PermissionSet {
+	set := entity.NewPermissionSet()
+	for _, item := range strings.Fields(scope) {
+		set[entity.Permission(item)] = struct{}{}
+	}
+	return set
+}
+
+func formatScope(set entity.PermissionSet) string {
+	items := make([]string, 0, len(set))
+	for _, permission := range set.List() {
+		items = append(items, string(permission))
+	}
+	return strings.Join(items, " ")
+}
+
+func (uc *authUseCase) completeLogin(user *entity.User, clientIP, userAgent string) (*model.LoginResponse, error) {
+	if err := uc.throttle.RecordSuccess(accountThrottleKey(user, "")); err != nil {
+		return nil, err
+	}
+
+	session, err := uc.sessionRepo.Create(&entity.Session{
+		UserID:    user.ID,
+		IPAddress: clientIP,
+		UserAgent: userAgent,
+		ExpiresAt: time.Now().Add(accessTokenTTL),
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate JWT token
-	token, err := uc.generateToken(user)
+	token, err := uc.generateToken(user, session.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
@@ -96,13 +667,65 @@ func (uc *authUseCase) Login(req *model.LoginRequest) (*model.LoginResponse, err
 	}, nil
 }
 
+func (uc *authUseCase) loginFailed(account, clientIP string) error {
+	if err := uc.throttle.RecordFailure(account, clientIP); err != nil {
+		return err
+	}
+	return fmt.Errorf("invalid credentials")
+}
+
 func (uc *authUseCase) VerifyToken(tokenString string) (*JWTClaims, error) {
+	claims, err := uc.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// MFA challenge tokens only unlock the MFA login endpoints
+	if claims.Purpose != "" {
+		return nil, fmt.Errorf("invalid token: unexpected token purpose")
+	}
+
+	// Tokens issued before a password reset carry an outdated version
+	user, err := uc.users.Get(claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: user not found")
+	}
+	if user.TokenVersion != claims.TokenVersion {
+		return nil, fmt.Errorf("invalid token: token has been revoked")
+	}
+	if user.IsDisabled() {
+		return nil, fmt.Errorf("invalid token: %w", ErrAccountDisabled)
+	}
+
+	// The token may predate a rename or role change
+	if uc.config.Auth.RevalidateUser {
+		claims.Username = user.Username
+		claims.Role = user.Role
+	}
+
+	// Impersonation tokens are not tied to a login of the user
+	if claims.SessionID != 0 {
+		now := time.Now()
+		session, err := uc.sessionRepo.GetByID(claims.SessionID)
+		if err != nil || session.UserID != claims.UserID || !session.IsActive(now) {
+			return nil, fmt.Errorf("invalid token: session has been revoked")
+		}
+		// Failing to record activity must not fail the request
+		if err := uc.sessionRepo.Touch(session.ID, now); err != nil {
+			log.Printf("failed to update last seen time of session %d: %v", session.ID, err)
+		}
+	}
+
+	return claims, nil
+}
+
+func (uc *authUseCase) parseToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(uc.config.JWT.SecretKey), nil
-	})
+	}, jwt.WithAudience(uc.config.JWT.Audience))
 
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
@@ -116,17 +739,54 @@ func (uc *authUseCase) VerifyToken(tokenString string) (*JWTClaims, error) {
 	return claims, nil
 }
 
-func (uc *authUseCase) generateToken(user *entity.User) (string, error) {
+func (uc *authUseCase) verifyChallengeToken(tokenString string) (*entity.User, error) {
+	claims, err := uc.parseToken(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired mfa token")
+	}
+	if claims.Purpose != tokenPurposeMFAChallenge {
+		return nil, fmt.Errorf("invalid or expired mfa token")
+	}
+
+	user, err := uc.userRepo.GetByID(claims.UserID)
+	if err != nil || user.TokenVersion != claims.TokenVersion || user.IsDisabled() {
+		return nil, fmt.Errorf("invalid or expired mfa token")
+	}
+
+	return user, nil
+}
+
+func (uc *authUseCase) generateToken(user *entity.User, sessionID int) (string, error) {
+	return uc.signToken(user, tokenOptions{sessionID: sessionID}, accessTokenTTL)
+}
+
+func (uc *authUseCase) generateChallengeToken(user *entity.User) (string, error) {
+	return uc.signToken(user, tokenOptions{purpose: tokenPurposeMFAChallenge}, uc.config.MFA.ChallengeTTL)
+}
+
+func (uc *authUseCase) signToken(user *entity.User, opts tokenOptions, ttl time.Duration) (string, error) {
+	audience := opts.audience
+	if audience == "" {
+		audience = uc.config.JWT.Audience
+	}
+
+	now := time.Now()
 	claims := &JWTClaims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Role:     user.Role,
+		UserID:       user.ID,
+		Username:     user.Username,
+		Role:         user.Role,
+		TokenVersion: user.TokenVersion,
+		Purpose:      opts.purpose,
+		Act:          opts.act,
+		SessionID:    opts.sessionID,
+		Scope:        opts.scope,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    uc.config.JWT.Issuer,
 			Subject:   fmt.Sprintf("%d", user.ID),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Audience:  jwt.ClaimStrings{audience},
 		},
 	}
 
@@ -0,0 +1,422 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/config"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/hasher"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+// fakeUserRepository keeps users in memory. Only the lookups used by login
+// are implemented.
+type fakeUserRepository struct {
+	repository.UserRepository
+	users map[string]*entity.User
+}
+
+func (r *fakeUserRepository) GetByUsername(username string) (*entity.User, error) {
+	user, ok := r.users[strings.ToLower(username)]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeUserRepository) Create(user *entity.User) (*entity.User, error) {
+	created := *user
+	created.ID = len(r.users) + 1
+	r.users[strings.ToLower(user.Username)] = &created
+	copied := created
+	return &copied, nil
+}
+
+func (r *fakeUserRepository) GetByID(id int) (*entity.User, error) {
+	for _, user := range r.users {
+		if user.ID == id {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *fakeUserRepository) GetByEmail(email string) (*entity.User, error) {
+	for _, user := range r.users {
+		if user.Email != "" && strings.EqualFold(user.Email, email) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *fakeUserRepository) MostCommonPasswordHash() (string, error) {
+	for _, user := range r.users {
+		return user.Password, nil
+	}
+	return "", nil
+}
+
+// fakeRoleUseCase grants the permissions listed per role.
+type fakeRoleUseCase struct {
+	RoleUseCase
+	roles map[entity.Role][]entity.Permission
+}
+
+func (uc *fakeRoleUseCase) PermissionsFor(role entity.Role) (entity.PermissionSet, error) {
+	return entity.NewPermissionSet(uc.roles[role]...), nil
+}
+
+// fakeAuditUseCase keeps recorded entries in memory.
+type fakeAuditUseCase struct {
+	AuditUseCase
+	entries []*entity.AuditLog
+}
+
+func (uc *fakeAuditUseCase) Record(entry *entity.AuditLog) error {
+	uc.entries = append(uc.entries, entry)
+	return nil
+}
+
+// slowEmailUseCase takes as long as a mail server would to accept a message.
+type slowEmailUseCase struct {
+	EmailUseCase
+	delay time.Duration
+}
+
+func (uc *slowEmailUseCase) SendVerification(userID int) error {
+	time.Sleep(uc.delay)
+	return nil
+}
+
+// TestRegisterTimingDoesNotRevealAccounts compares the median time of
+// registrations that create an account with ones for a taken username or
+// email, with generic responses enabled.
+func TestRegisterTimingDoesNotRevealAccounts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing test")
+	}
+
+	const (
+		samples   = 20
+		tolerance = 0.25
+	)
+
+	passwordHasher, err := hasher.New(config.PasswordHashConfig{Algorithm: hasher.AlgorithmBcrypt, BcryptCost: 8, Argon2Time: 1, Argon2Memory: 8 * 1024, Argon2Threads: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := NewCredentialPolicy(config.PasswordConfig{MinLength: 8, UsernameMinLength: 3, UsernameMaxLength: 32}, nil)
+
+	tests := []struct {
+		name string
+		// taken returns a request for an existing account
+		taken func(i int) *model.RegisterRequest
+	}{
+		{"taken username", func(i int) *model.RegisterRequest {
+			return &model.RegisterRequest{Username: "alice", Password: "another password", Email: fmt.Sprintf("other%d@example.com", i)}
+		}},
+		{"taken email", func(i int) *model.RegisterRequest {
+			return &model.RegisterRequest{Username: fmt.Sprintf("other%d", i), Password: "another password", Email: "alice@example.com"}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := &fakeUserRepository{users: map[string]*entity.User{
+				"alice": {ID: 1, Username: "alice", Email: "alice@example.com"},
+			}}
+			cfg := &config.Config{}
+			cfg.Auth.GenericRegistrationResponse = true
+			// A slow mail server makes a synchronous send stand out
+			emailUseCase := &slowEmailUseCase{delay: 100 * time.Millisecond}
+			uc := NewAuthUseCase(userRepo, repository.NewMemoryLoginAttemptRepository(), nil, nil, nil, emailUseCase, nil, policy, passwordHasher, NewUserCache(userRepo, 0), cfg)
+
+			register := func(req *model.RegisterRequest) time.Duration {
+				start := time.Now()
+				if _, err := uc.Register(req); err != nil {
+					t.Fatalf("register %s: %v", req.Username, err)
+				}
+				return time.Since(start)
+			}
+
+			var created, taken []time.Duration
+			for i := 0; i < samples; i++ {
+				created = append(created, register(&model.RegisterRequest{Username: fmt.Sprintf("new%d", i), Password: "a new password", Email: fmt.Sprintf("new%d@example.com", i)}))
+				taken = append(taken, register(tt.taken(i)))
+			}
+
+			createdMedian, takenMedian := median(created), median(taken)
+			diff := float64(createdMedian-takenMedian) / float64(createdMedian)
+			if diff < -tolerance || diff > tolerance {
+				t.Errorf("median registration time differs by %.0f%%: new account %v, %s %v", diff*100, createdMedian, tt.name, takenMedian)
+			}
+		})
+	}
+}
+
+func TestImpersonate(t *testing.T) {
+	supportPermissions := []entity.Permission{
+		entity.PermissionProfileRead,
+		entity.PermissionTodosReadOwn,
+		entity.PermissionTodosWriteOwn,
+		entity.PermissionUsersRead,
+		entity.PermissionUsersImpersonate,
+	}
+	roles := &fakeRoleUseCase{roles: map[entity.Role][]entity.Permission{
+		entity.UserRole:  {entity.PermissionProfileRead, entity.PermissionTodosReadOwn, entity.PermissionTodosWriteOwn},
+		entity.AdminRole: entity.Permissions,
+		"support":        supportPermissions,
+		"manager":        {entity.PermissionProfileRead, entity.PermissionUsersManage},
+		"role-admin":     {entity.PermissionRolesManage},
+	}}
+	userRepo := &fakeUserRepository{users: map[string]*entity.User{
+		"support": {ID: 1, Username: "support", Role: "support"},
+		"admin":   {ID: 2, Username: "admin", Role: entity.AdminRole},
+		"alice":   {ID: 3, Username: "alice", Role: entity.UserRole},
+		"bob":     {ID: 4, Username: "bob", Role: "manager"},
+		"carol":   {ID: 5, Username: "carol", Role: "role-admin"},
+		"dave":    {ID: 6, Username: "dave", Role: "support"},
+	}}
+
+	supportActor := &entity.Actor{UserID: 1, Permissions: entity.NewPermissionSet(supportPermissions...)}
+	adminActor := &entity.Actor{UserID: 2, Permissions: entity.NewPermissionSet(entity.Permissions...)}
+
+	tests := []struct {
+		name           string
+		actor          *entity.Actor
+		target         int
+		wantErr        bool
+		wantPermission entity.Permission
+	}{
+		{"user with fewer permissions", supportActor, 3, false, ""},
+		{"user with the same permissions", supportActor, 6, false, ""},
+		{"custom role granting users:manage", supportActor, 4, true, entity.PermissionUsersManage},
+		{"custom role granting roles:manage", supportActor, 5, true, entity.PermissionRolesManage},
+		{"admin impersonating a custom role", adminActor, 4, false, ""},
+		{"admin impersonating support", adminActor, 1, false, ""},
+		{"admin target", supportActor, 2, true, ""},
+		{"yourself", supportActor, 1, true, ""},
+		{"without users:impersonate", &entity.Actor{UserID: 3, Permissions: entity.NewPermissionSet(entity.PermissionProfileRead)}, 6, true, entity.PermissionUsersImpersonate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &fakeAuditUseCase{}
+			cfg := &config.Config{}
+			cfg.JWT.SecretKey = "test-secret"
+			cfg.Admin.ImpersonationTTL = time.Minute
+			uc := NewAuthUseCase(userRepo, repository.NewMemoryLoginAttemptRepository(), nil, nil, audit, nil, roles, nil, nil, NewUserCache(userRepo, 0), cfg)
+
+			response, err := uc.Impersonate(tt.actor, tt.target, &model.ImpersonateRequest{Reason: "test"})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Impersonate() error = %v", err)
+				}
+				if response.Token == "" || response.User.ID != tt.target {
+					t.Errorf("Impersonate() = %+v, want a token for user %d", response, tt.target)
+				}
+				if len(audit.entries) != 1 {
+					t.Errorf("recorded %d audit entries, want 1", len(audit.entries))
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("Impersonate() succeeded, want an error")
+			}
+			if tt.wantPermission != "" {
+				var forbidden *ForbiddenError
+				if !errors.As(err, &forbidden) || forbidden.Permission != tt.wantPermission {
+					t.Errorf("Impersonate() error = %v, want permission %s required", err, tt.wantPermission)
+				}
+			}
+			if len(audit.entries) != 0 {
+				t.Errorf("recorded %d audit entries for a refused impersonation", len(audit.entries))
+			}
+		})
+	}
+}
+
+// TestLoginTimingDoesNotRevealUsers compares the median time of failed
+// logins for an existing and an unknown username. Medians of interleaved
+// samples keep scheduling noise from deciding the outcome.
+func TestLoginTimingDoesNotRevealUsers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("timing test")
+	}
+
+	const (
+		samples   = 30
+		tolerance = 0.25
+	)
+
+	bcryptConfig := config.PasswordHashConfig{Algorithm: hasher.AlgorithmBcrypt, BcryptCost: 8, Argon2Time: 1, Argon2Memory: 8 * 1024, Argon2Threads: 1}
+	argon2Config := config.PasswordHashConfig{Algorithm: hasher.AlgorithmArgon2id, BcryptCost: 8, Argon2Time: 1, Argon2Memory: 8 * 1024, Argon2Threads: 1}
+
+	tests := []struct {
+		name string
+		// stored hashes the users' passwords with these settings
+		stored config.PasswordHashConfig
+		// current is what the application is configured with
+		current config.PasswordHashConfig
+	}{
+		{"bcrypt", bcryptConfig, bcryptConfig},
+		{"argon2id", argon2Config, argon2Config},
+		{"legacy bcrypt after switching to argon2id", bcryptConfig, argon2Config},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storedHasher, err := hasher.New(tt.stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			currentHasher, err := hasher.New(tt.current)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			password, err := storedHasher.Hash("correct horse battery staple")
+			if err != nil {
+				t.Fatal(err)
+			}
+			userRepo := &fakeUserRepository{users: map[string]*entity.User{
+				"alice": {ID: 1, Username: "alice", Password: password, Role: entity.UserRole},
+			}}
+
+			cfg := &config.Config{}
+			uc := NewAuthUseCase(userRepo, repository.NewMemoryLoginAttemptRepository(), nil, nil, nil, nil, nil, nil, currentHasher, NewUserCache(userRepo, 0), cfg)
+
+			login := func(username string) time.Duration {
+				start := time.Now()
+				if _, err := uc.Login(&model.LoginRequest{Username: username, Password: "wrong password"}); err == nil {
+					t.Fatalf("login of %s succeeded with a wrong password", username)
+				}
+				return time.Since(start)
+			}
+
+			// The first unknown login makes the dummy hash
+			login("nobody")
+			login("alice")
+
+			var known, unknown []time.Duration
+			for i := 0; i < samples; i++ {
+				known = append(known, login("alice"))
+				unknown = append(unknown, login("nobody"))
+			}
+
+			knownMedian, unknownMedian := median(known), median(unknown)
+			diff := float64(knownMedian-unknownMedian) / float64(knownMedian)
+			if diff < -tolerance || diff > tolerance {
+				t.Errorf("median login time differs by %.0f%%: existing user %v, unknown user %v", diff*100, knownMedian, unknownMedian)
+			}
+		})
+	}
+}
+
+func median(durations []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+func TestFindUserByLogin(t *testing.T) {
+	verified := time.Now()
+	userRepo := &fakeUserRepository{users: map[string]*entity.User{
+		"alice":    {ID: 1, Username: "alice", Email: "alice@example.com", EmailVerifiedAt: &verified},
+		"bob":      {ID: 2, Username: "bob", Email: "bob@example.com"},
+		"carol@hq": {ID: 3, Username: "carol@hq"},
+	}}
+
+	tests := []struct {
+		name    string
+		login   string
+		wantID  int
+		wantErr bool
+	}{
+		{"username", "alice", 1, false},
+		{"username in other case", "ALICE", 1, false},
+		{"verified email", "alice@example.com", 1, false},
+		{"verified email in other case with spaces", "  Alice@Example.com ", 1, false},
+		{"unverified email", "bob@example.com", 0, true},
+		{"legacy username containing @", "carol@hq", 3, false},
+		{"unknown email", "nobody@example.com", 0, true},
+		{"unknown username", "nobody", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := findUserByLogin(userRepo, tt.login)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("findUserByLogin(%q) = user %d, want an error", tt.login, user.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("findUserByLogin(%q): %v", tt.login, err)
+			}
+			if user.ID != tt.wantID {
+				t.Errorf("findUserByLogin(%q) = user %d, want %d", tt.login, user.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestLoginThrottleCountsPerAccount(t *testing.T) {
+	passwordHasher, err := hasher.New(config.PasswordHashConfig{Algorithm: hasher.AlgorithmBcrypt, BcryptCost: 4, Argon2Time: 1, Argon2Memory: 8 * 1024, Argon2Threads: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, err := passwordHasher.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified := time.Now()
+
+	tests := []struct {
+		name string
+		// failures are made with these logins, then logins is locked
+		failures []string
+		login    string
+	}{
+		{"username and email share a counter", []string{"alice", "alice@example.com", "ALICE"}, "Alice@Example.com"},
+		{"padded email", []string{" alice@example.com", "alice@example.com  ", "\talice@example.com"}, "alice"},
+		{"unknown login in other case or padded", []string{"nobody", " NOBODY ", "Nobody"}, "nobody"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := &fakeUserRepository{users: map[string]*entity.User{
+				"alice": {ID: 1, Username: "alice", Password: password, Email: "alice@example.com", EmailVerifiedAt: &verified},
+			}}
+			cfg := &config.Config{}
+			cfg.Login = config.LoginConfig{MaxAttempts: len(tt.failures), LockoutDuration: time.Minute, AttemptWindow: time.Minute}
+			uc := NewAuthUseCase(userRepo, repository.NewMemoryLoginAttemptRepository(), nil, nil, nil, nil, nil, nil, passwordHasher, NewUserCache(userRepo, 0), cfg)
+
+			for _, login := range tt.failures {
+				_, err := uc.Login(&model.LoginRequest{Username: login, Password: "wrong password"})
+				var tooMany *TooManyAttemptsError
+				if err == nil || errors.As(err, &tooMany) {
+					t.Fatalf("login as %q = %v, want invalid credentials", login, err)
+				}
+			}
+
+			_, err := uc.Login(&model.LoginRequest{Username: tt.login, Password: "wrong password"})
+			var tooMany *TooManyAttemptsError
+			if !errors.As(err, &tooMany) {
+				t.Errorf("login as %q after %d failures = %v, want too many attempts", tt.login, len(tt.failures), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/config"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/hasher"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/mailer"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+// maxEmailLength matches the users.email column.
+const maxEmailLength = 254
+
+// emailVerificationKeyLabel derives the verification signing key from
+// JWT_SECRET, so a verification token can never pass as an access token.
+const emailVerificationKeyLabel = "email-verification"
+
+type EmailUseCase interface {
+	// SendVerification emails a verification link to the user's current
+	// address. It does nothing when the address is empty or already verified.
+	SendVerification(userID int) error
+	Verify(req *model.VerifyEmailRequest) (*entity.User, error)
+	ChangeEmail(userID int, req *model.ChangeEmailRequest) (*entity.User, error)
+}
+
+// emailVerificationClaims ties the token to the address it was sent to, so
+// changing the address invalidates links sent to the old one.
+type emailVerificationClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+type emailUseCase struct {
+	userRepo       repository.UserRepository
+	mailer         mailer.Mailer
+	passwordHasher hasher.Hasher
+	config         *config.Config
+	signingKey     []byte
+}
+
+func NewEmailUseCase(
+	userRepo repository.UserRepository,
+	mailer mailer.Mailer,
+	passwordHasher hasher.Hasher,
+	config *config.Config,
+) EmailUseCase {
+	mac := hmac.New(sha256.New, []byte(config.JWT.SecretKey))
+	mac.Write([]byte(emailVerificationKeyLabel))
+
+	return &emailUseCase{
+		userRepo:       userRepo,
+		mailer:         mailer,
+		passwordHasher: passwordHasher,
+		config:         config,
+		signingKey:     mac.Sum(nil),
+	}
+}
+
+func (uc *emailUseCase) SendVerification(userID int) error {
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Email == "" || user.HasVerifiedEmail() {
+		return nil
+	}
+
+	token, err := uc.signToken(user)
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nUse this token to verify your email address:\n\n%s\n", user.Username, token)
+	if uc.config.Mail.VerificationURL != "" {
+		body = fmt.Sprintf("Hi %s,\n\nOpen this link to verify your email address:\n\n%s%s\n", user.Username, uc.config.Mail.VerificationURL, token)
+	}
+	body += fmt.Sprintf("\nThe link expires in %s. If you did not add this address, ignore this email.\n", uc.config.Mail.VerificationTTL)
+
+	err = uc.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body:    body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	return nil
+}
+
+func (uc *emailUseCase) Verify(req *model.VerifyEmailRequest) (*entity.User, error) {
+	invalid := fmt.Errorf("invalid or expired verification token")
+
+	claims := &emailVerificationClaims{}
+	_, err := jwt.ParseWithClaims(req.Token, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return uc.signingKey, nil
+	})
+	if err != nil {
+		return nil, invalid
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, invalid
+	}
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil || !strings.EqualFold(user.Email, claims.Email) {
+		return nil, invalid
+	}
+
+	if !user.HasVerifiedEmail() {
+		user, err = uc.userRepo.SetEmailVerified(user.ID, claims.Email, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify email: %w", err)
+		}
+	}
+
+	// Remove password from response
+	user.Password = ""
+	return user, nil
+}
+
+// ChangeEmail sets a new, unverified address and sends a verification link to
+// it. The current password is required, since the address receives password
+// resets once verified.
+func (uc *emailUseCase) ChangeEmail(userID int, req *model.ChangeEmailRequest) (*entity.User, error) {
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if ok, _ := uc.passwordHasher.Verify(user.Password, req.Password); !ok {
+		return nil, fmt.Errorf("invalid current password")
+	}
+
+	email := normalizeEmail(req.Email)
+	validationErr := &ValidationError{}
+	validateEmail(validationErr, "email", email)
+	if err := validationErr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(user.Email, email) {
+		user.Password = ""
+		return user, nil
+	}
+
+	if existing, err := uc.userRepo.GetByEmail(email); err == nil && existing.ID != user.ID {
+		return nil, ErrEmailTaken
+	}
+
+	updatedUser, err := uc.userRepo.SetEmail(user.ID, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to change email: %w", err)
+	}
+
+	if err := uc.SendVerification(updatedUser.ID); err != nil {
+		return nil, err
+	}
+
+	// Remove password from response
+	updatedUser.Password = ""
+	return updatedUser, nil
+}
+
+func (uc *emailUseCase) signToken(user *entity.User) (string, error) {
+	now := time.Now()
+	claims := &emailVerificationClaims{
+		Email: user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    uc.config.JWT.Issuer,
+			Subject:   strconv.Itoa(user.ID),
+			ExpiresAt: jwt.NewNumericDate(now.Add(uc.config.Mail.VerificationTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(uc.signingKey)
+}
+
+func normalizeEmail(email string) string {
+	return strings.TrimSpace(email)
+}
+
+// validateEmail accepts a bare address only; display names such as
+// "Name <user@example.com>" are rejected.
+func validateEmail(validationErr *ValidationError, field, email string) {
+	if len(email) > maxEmailLength {
+		validationErr.Add(field, fmt.Sprintf("must be at most %d characters", maxEmailLength))
+		return
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		validationErr.Add(field, "must be a valid email address")
+	}
+}
+
+// findUserByLogin resolves the identifier a user signs in with, which is
+// either a username or a verified email address. Unverified addresses are
+// not accepted since anyone can claim one. Usernames created before the
+// username policy may contain '@', so a login that matches no verified
+// address is tried as a username too.
+func findUserByLogin(userRepo repository.UserRepository, login string) (*entity.User, error) {
+	if strings.Contains(login, "@") {
+		user, err := userRepo.GetByEmail(normalizeEmail(login))
+		if err == nil && user.HasVerifiedEmail() {
+			return user, nil
+		}
+	}
+	return userRepo.GetByUsername(login)
+}
+
+// isDuplicateAccount reports errors that reveal an existing account.
+func isDuplicateAccount(err error) bool {
+	return errors.Is(err, ErrUserExists) || errors.Is(err, ErrEmailTaken)
+}
@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+)
+
+// ErrAccountDisabled is returned when a disabled account tries to sign in or
+// use a token.
+var ErrAccountDisabled = errors.New("account is disabled")
+
+// ErrUserExists is returned when registering a username that is taken.
+var ErrUserExists = errors.New("user already exists")
+
+// ErrEmailTaken is returned when an email address belongs to another account.
+var ErrEmailTaken = errors.New("email address is already in use")
+
+// ErrPreconditionRequired is returned when a todo is changed without naming
+// the version the change is based on, while that is required.
+var ErrPreconditionRequired = errors.New("If-Match header with the todo's ETag is required")
+
+// PreconditionFailedError is returned when a todo is no longer at the version
+// a change was based on. Version is the current one, or 0 when unknown.
+type PreconditionFailedError struct {
+	Version int
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return "todo has been modified since it was read; fetch it and retry"
+}
+
+// ErrRolledBack is reported for operations of an atomic bulk request that
+// were undone or skipped because another operation failed.
+var ErrRolledBack = errors.New("not applied because another operation failed")
+
+// InvalidTransitionError is returned when a todo cannot move from its current
+// status to the requested one.
+type InvalidTransitionError struct {
+	From    entity.TodoStatus
+	To      entity.TodoStatus
+	Allowed []entity.TodoStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("cannot change status from %s to %s", e.From, e.To)
+}
+
+// ConflictError is returned when an action would affect open todos the
+// caller has not confirmed. Repeating the request with force proceeds.
+type ConflictError struct {
+	Message   string
+	OpenTodos int
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// ValidationError reports input that was rejected by a use case, keyed by
+// request field so clients can show the messages next to the right input.
+type ValidationError struct {
+	Fields map[string][]string `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return "validation failed: " + strings.Join(fields, ", ")
+}
+
+// Add records a message for field.
+func (e *ValidationError) Add(field, message string) {
+	if e.Fields == nil {
+		e.Fields = make(map[string][]string)
+	}
+	e.Fields[field] = append(e.Fields[field], message)
+}
+
+// ErrOrNil returns e when it holds at least one message, otherwise nil.
+func (e *ValidationError) ErrOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// TooManyAttemptsError is returned when login attempts are throttled.
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("too many login attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// ForbiddenError is returned when the actor lacks the permission for an action.
+type ForbiddenError struct {
+	Permission entity.Permission
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("permission %s required", e.Permission)
+}
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/config"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/metrics"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+// loginThrottle applies exponential backoff and lockout to failed logins,
+// keyed both by account and by client IP.
+type loginThrottle struct {
+	attemptRepo repository.LoginAttemptRepository
+	config      config.LoginConfig
+}
+
+func newLoginThrottle(attemptRepo repository.LoginAttemptRepository, cfg config.LoginConfig) *loginThrottle {
+	return &loginThrottle{
+		attemptRepo: attemptRepo,
+		config:      cfg,
+	}
+}
+
+// accountThrottleKey returns the per-account key for a login. Resolved users
+// are keyed by ID, so signing in by username and by email counts against the
+// same account. Logins matching no user are keyed by the identifier,
+// normalized so that case or padding does not start a new counter.
+func accountThrottleKey(user *entity.User, login string) string {
+	if user != nil {
+		return fmt.Sprintf("user:%d", user.ID)
+	}
+	return "login:" + strings.ToLower(strings.TrimSpace(login))
+}
+
+func throttleKeys(account, clientIP string) []string {
+	keys := []string{account}
+	if clientIP != "" {
+		keys = append(keys, "ip:"+clientIP)
+	}
+	return keys
+}
+
+// Check returns a *TooManyAttemptsError when any of the keys is locked.
+func (t *loginThrottle) Check(account, clientIP string) error {
+	now := time.Now()
+
+	var retryAfter time.Duration
+	for _, key := range throttleKeys(account, clientIP) {
+		attempt, err := t.attemptRepo.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to check login attempts: %w", err)
+		}
+		if attempt.IsLocked(now) {
+			if wait := attempt.LockedUntil.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+
+	if retryAfter > 0 {
+		metrics.LoginThrottled.Add(1)
+		return &TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+func (t *loginThrottle) RecordFailure(account, clientIP string) error {
+	now := time.Now()
+	metrics.LoginFailures.Add(1)
+
+	for _, key := range throttleKeys(account, clientIP) {
+		attempt, err := t.attemptRepo.RecordFailure(key, now, t.config.AttemptWindow)
+		if err != nil {
+			return err
+		}
+
+		delay := t.delayFor(attempt.Failures)
+		if delay <= 0 {
+			continue
+		}
+
+		if attempt.Failures >= t.config.MaxAttempts {
+			metrics.LoginLockouts.Add(1)
+		}
+		if err := t.attemptRepo.Lock(key, now.Add(delay)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecordSuccess clears the account counter. The IP counter is left alone so
+// an attacker cannot reset it by interleaving logins to their own account.
+func (t *loginThrottle) RecordSuccess(account string) error {
+	return t.attemptRepo.Reset(account)
+}
+
+func (t *loginThrottle) delayFor(failures int) time.Duration {
+	if t.config.MaxAttempts > 0 && failures >= t.config.MaxAttempts {
+		return t.config.LockoutDuration
+	}
+	if t.config.BackoffAfter <= 0 || failures < t.config.BackoffAfter {
+		return 0
+	}
+
+	delay := t.config.BackoffBase
+	for i := t.config.BackoffAfter; i < failures && delay < t.config.LockoutDuration; i++ {
+		delay *= 2
+	}
+	if delay > t.config.LockoutDuration {
+		delay = t.config.LockoutDuration
+	}
+	return delay
+}
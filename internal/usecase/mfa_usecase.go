@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/config"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/totp"
+)
+
+const (
+	recoveryCodeCount = 10
+	// totpSkew accepts codes from one period before or after the current one
+	totpSkew = 1
+)
+
+type MFAUseCase interface {
+	Enroll(userID int) (*model.MFAEnrollResponse, error)
+	Confirm(userID int, code string) ([]string, error)
+	Disable(userID int, code string) error
+	IsEnabled(userID int) (bool, error)
+	Verify(userID int, code, recoveryCode string) error
+}
+
+type mfaUseCase struct {
+	mfaRepo  repository.MFARepository
+	userRepo repository.UserRepository
+	config   *config.Config
+}
+
+func NewMFAUseCase(mfaRepo repository.MFARepository, userRepo repository.UserRepository, config *config.Config) MFAUseCase {
+	return &mfaUseCase{
+		mfaRepo:  mfaRepo,
+		userRepo: userRepo,
+		config:   config,
+	}
+}
+
+// Enroll generates a fresh secret. It is not used for login until Confirm
+// succeeds with a code from the authenticator app.
+func (uc *mfaUseCase) Enroll(userID int) (*model.MFAEnrollResponse, error) {
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := uc.mfaRepo.SavePending(userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.MFAEnrollResponse{
+		Secret: secret,
+		URI:    totp.URI(uc.config.MFA.Issuer, user.Username, secret),
+	}, nil
+}
+
+// Confirm enables MFA and returns a new set of recovery codes. The codes are
+// stored hashed and cannot be retrieved again.
+func (uc *mfaUseCase) Confirm(userID int, code string) ([]string, error) {
+	mfa, err := uc.mfaRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("no pending mfa enrollment")
+	}
+	if mfa.Enabled {
+		return nil, fmt.Errorf("mfa is already enabled")
+	}
+
+	step, ok := totp.Validate(mfa.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, fmt.Errorf("invalid mfa code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	if err := uc.mfaRepo.Enable(userID, step); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (uc *mfaUseCase) Disable(userID int, code string) error {
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if uc.config.MFA.RequireForAdmin && user.Role == entity.AdminRole {
+		return fmt.Errorf("mfa is required for admin accounts")
+	}
+
+	if err := uc.Verify(userID, code, ""); err != nil {
+		return err
+	}
+
+	return uc.mfaRepo.Delete(userID)
+}
+
+func (uc *mfaUseCase) IsEnabled(userID int) (bool, error) {
+	mfa, err := uc.mfaRepo.GetByUserID(userID)
+	if err != nil {
+		return false, nil
+	}
+
+	return mfa.Enabled, nil
+}
+
+// Verify accepts either a TOTP code or an unused recovery code.
+func (uc *mfaUseCase) Verify(userID int, code, recoveryCode string) error {
+	mfa, err := uc.mfaRepo.GetByUserID(userID)
+	if err != nil || !mfa.Enabled {
+		return fmt.Errorf("mfa is not enabled")
+	}
+
+	if recoveryCode != "" {
+		if err := uc.mfaRepo.UseRecoveryCode(userID, hashRecoveryCode(recoveryCode)); err != nil {
+			return fmt.Errorf("invalid recovery code")
+		}
+		return nil
+	}
+
+	step, ok := totp.Validate(mfa.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return fmt.Errorf("invalid mfa code")
+	}
+
+	if err := uc.mfaRepo.MarkStepUsed(userID, step); err != nil {
+		return fmt.Errorf("invalid mfa code")
+	}
+
+	return nil
+}
+
+// generateRecoveryCodes returns codes formatted as xxxxx-xxxxx together with
+// their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	// 32 characters so every random byte maps evenly; look-alikes are left out
+	const alphabet = "abcdefghjkmnpqrstuvwxyz023456789"
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		for j := range b {
+			b[j] = alphabet[int(b[j])%len(alphabet)]
+		}
+
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/config"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/hasher"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// CredentialPolicy validates usernames and passwords before they are stored.
+type CredentialPolicy interface {
+	ValidateUsername(username string) []string
+	ValidatePassword(password string) ([]string, error)
+}
+
+type credentialPolicy struct {
+	config       config.PasswordConfig
+	breachedRepo repository.BreachedPasswordRepository
+}
+
+// NewCredentialPolicy builds the policy from config. breachedRepo may be nil,
+// in which case the breached-password check is skipped.
+func NewCredentialPolicy(cfg config.PasswordConfig, breachedRepo repository.BreachedPasswordRepository) CredentialPolicy {
+	// bcrypt silently ignores input past 72 bytes, so longer passwords are
+	// rejected instead
+	if maxBytes := hasher.MaxPasswordBytes(cfg.Hash.Algorithm); cfg.MaxLength <= 0 || cfg.MaxLength > maxBytes {
+		cfg.MaxLength = maxBytes
+	}
+
+	return &credentialPolicy{
+		config:       cfg,
+		breachedRepo: breachedRepo,
+	}
+}
+
+func (p *credentialPolicy) ValidateUsername(username string) []string {
+	var problems []string
+
+	length := utf8.RuneCountInString(username)
+	if length < p.config.UsernameMinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.config.UsernameMinLength))
+	}
+	if length > p.config.UsernameMaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters", p.config.UsernameMaxLength))
+	}
+	if !usernamePattern.MatchString(username) {
+		problems = append(problems, "may only contain letters, digits, '.', '_' and '-' and must start with a letter or digit")
+	}
+
+	return problems
+}
+
+func (p *credentialPolicy) ValidatePassword(password string) ([]string, error) {
+	var problems []string
+
+	if utf8.RuneCountInString(password) < p.config.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.config.MinLength))
+	}
+	// Measured in bytes because that is what the hash function sees
+	if len(password) > p.config.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", p.config.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.config.RequireUpper && !hasUpper {
+		problems = append(problems, "must contain an uppercase letter")
+	}
+	if p.config.RequireLower && !hasLower {
+		problems = append(problems, "must contain a lowercase letter")
+	}
+	if p.config.RequireDigit && !hasDigit {
+		problems = append(problems, "must contain a digit")
+	}
+	if p.config.RequireSymbol && !hasSymbol {
+		problems = append(problems, "must contain a symbol")
+	}
+
+	breached, err := p.isBreached(password)
+	if err != nil {
+		return nil, err
+	}
+	if breached {
+		problems = append(problems, "has appeared in a data breach, choose a different password")
+	}
+
+	return problems, nil
+}
+
+func (p *credentialPolicy) isBreached(password string) (bool, error) {
+	if p.breachedRepo == nil {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, err := p.breachedRepo.GetSuffixesByPrefix(hash[:5])
+	if err != nil {
+		return false, fmt.Errorf("failed to check breached passwords: %w", err)
+	}
+
+	for _, suffix := range suffixes {
+		if suffix == hash[5:] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// validateCredentials runs the policy over a username/password pair and
+// returns a *ValidationError keyed by the given field names.
+func validateCredentials(policy CredentialPolicy, usernameField, username, passwordField, password string) error {
+	validationErr := &ValidationError{}
+
+	if usernameField != "" {
+		for _, problem := range policy.ValidateUsername(username) {
+			validationErr.Add(usernameField, problem)
+		}
+	}
+
+	problems, err := policy.ValidatePassword(password)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		validationErr.Add(passwordField, problem)
+	}
+
+	return validationErr.ErrOrNil()
+}
@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/config"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/hasher"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/notifier"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+type PasswordUseCase interface {
+	ChangePassword(userID int, req *model.ChangePasswordRequest) error
+	RequestReset(req *model.ForgotPasswordRequest)
+	ResetPassword(req *model.ResetPasswordRequest) error
+}
+
+type passwordUseCase struct {
+	userRepo       repository.UserRepository
+	resetRepo      repository.PasswordResetRepository
+	sessionRepo    repository.SessionRepository
+	apiKeyRepo     repository.APIKeyRepository
+	notifier       notifier.Notifier
+	policy         CredentialPolicy
+	passwordHasher hasher.Hasher
+	users          *UserCache
+	config         *config.Config
+}
+
+func NewPasswordUseCase(
+	userRepo repository.UserRepository,
+	resetRepo repository.PasswordResetRepository,
+	sessionRepo repository.SessionRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	notifier notifier.Notifier,
+	policy CredentialPolicy,
+	passwordHasher hasher.Hasher,
+	users *UserCache,
+	config *config.Config,
+) PasswordUseCase {
+	return &passwordUseCase{
+		userRepo:       userRepo,
+		resetRepo:      resetRepo,
+		sessionRepo:    sessionRepo,
+		apiKeyRepo:     apiKeyRepo,
+		notifier:       notifier,
+		policy:         policy,
+		passwordHasher: passwordHasher,
+		users:          users,
+		config:         config,
+	}
+}
+
+func (uc *passwordUseCase) ChangePassword(userID int, req *model.ChangePasswordRequest) error {
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Require the current password so a stolen token alone cannot take over the account
+	if ok, _ := uc.passwordHasher.Verify(user.Password, req.OldPassword); !ok {
+		return fmt.Errorf("invalid current password")
+	}
+
+	if err := validateCredentials(uc.policy, "", "", "new_password", req.NewPassword); err != nil {
+		return err
+	}
+
+	return uc.setPassword(user, req.NewPassword, false)
+}
+
+// RequestReset issues a reset token and hands it to the notifier. Unknown
+// usernames and emails are silently ignored so the endpoint cannot be used to
+// discover accounts. For known ones the token is stored and sent in the
+// background, so neither the extra work nor a delivery failure shows in the
+// response; failures are only logged.
+func (uc *passwordUseCase) RequestReset(req *model.ForgotPasswordRequest) {
+	user, err := findUserByLogin(uc.userRepo, req.Username)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		if err := uc.sendResetToken(user); err != nil {
+			log.Printf("failed to send password reset token to user %d: %v", user.ID, err)
+		}
+	}()
+}
+
+func (uc *passwordUseCase) sendResetToken(user *entity.User) error {
+	token, err := generateResetToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	_, err = uc.resetRepo.Create(&entity.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: time.Now().Add(uc.config.Password.ResetTokenTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+
+	if err := uc.notifier.SendPasswordReset(user, token); err != nil {
+		return fmt.Errorf("failed to send reset token: %w", err)
+	}
+
+	return nil
+}
+
+func (uc *passwordUseCase) ResetPassword(req *model.ResetPasswordRequest) error {
+	// Validate first so a rejected password does not burn the token
+	if err := validateCredentials(uc.policy, "", "", "new_password", req.NewPassword); err != nil {
+		return err
+	}
+
+	resetToken, err := uc.resetRepo.GetByTokenHash(hashResetToken(req.Token))
+	if err != nil {
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	if err := uc.resetRepo.MarkUsed(resetToken.ID); err != nil {
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	user, err := uc.userRepo.GetByID(resetToken.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Whoever requested the reset may not be the only one holding a session
+	return uc.setPassword(user, req.NewPassword, true)
+}
+
+func (uc *passwordUseCase) setPassword(user *entity.User, password string, revokeSessions bool) error {
+	hashedPassword, err := uc.passwordHasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	if _, err := uc.userRepo.SetPassword(user.ID, hashedPassword, revokeSessions); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	uc.users.Invalidate(user.ID)
+
+	// A reset link sent before the change must not undo it
+	if err := uc.resetRepo.MarkAllUsedByUserID(user.ID); err != nil {
+		return err
+	}
+
+	// The token version already rejects old tokens; this keeps the session
+	// list in line with it. API keys are revoked too, so whoever took over
+	// the account cannot keep access through a key minted meanwhile.
+	if revokeSessions {
+		if err := uc.sessionRepo.RevokeAllByUserID(user.ID); err != nil {
+			return err
+		}
+		if err := uc.apiKeyRepo.RevokeAllByUserID(user.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func generateResetToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
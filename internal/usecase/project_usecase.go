@@ -0,0 +1,266 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+// maxProjectNameLength matches the projects.name column.
+const maxProjectNameLength = 100
+
+type ProjectUseCase interface {
+	GetByUserID(userID int, includeArchived bool) ([]*entity.Project, error)
+	GetByID(projectID, userID int) (*entity.Project, error)
+	GetTodos(projectID, userID int) ([]*entity.Todo, error)
+	Create(userID int, req *model.CreateProjectRequest) (*entity.Project, error)
+	Update(projectID, userID int, req *model.UpdateProjectRequest) (*entity.Project, error)
+	Delete(projectID, userID int) error
+	// Archive refuses with a ConflictError while the project has open todos,
+	// unless force is set, in which case they are cancelled. Its todos are
+	// archived with it, each recording a revision.
+	Archive(projectID int, actor *entity.Actor, force bool) (*entity.Project, error)
+	Unarchive(projectID, userID int) (*entity.Project, error)
+}
+
+type projectUseCase struct {
+	projectRepo repository.ProjectRepository
+	todoRepo    repository.TodoRepository
+	transactor  repository.Transactor
+}
+
+func NewProjectUseCase(projectRepo repository.ProjectRepository, todoRepo repository.TodoRepository, transactor repository.Transactor) ProjectUseCase {
+	return &projectUseCase{
+		projectRepo: projectRepo,
+		todoRepo:    todoRepo,
+		transactor:  transactor,
+	}
+}
+
+func (uc *projectUseCase) GetByUserID(userID int, includeArchived bool) ([]*entity.Project, error) {
+	projects, err := uc.projectRepo.GetByUserID(userID, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+
+	return projects, nil
+}
+
+func (uc *projectUseCase) GetByID(projectID, userID int) (*entity.Project, error) {
+	return uc.getOwned(projectID, userID)
+}
+
+func (uc *projectUseCase) GetTodos(projectID, userID int) ([]*entity.Todo, error) {
+	if _, err := uc.getOwned(projectID, userID); err != nil {
+		return nil, err
+	}
+
+	todos, err := uc.todoRepo.GetByProjectID(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todos: %w", err)
+	}
+
+	return todos, nil
+}
+
+func (uc *projectUseCase) Create(userID int, req *model.CreateProjectRequest) (*entity.Project, error) {
+	project := &entity.Project{
+		UserID: userID,
+		Name:   strings.TrimSpace(req.Name),
+		Color:  req.Color,
+	}
+	if req.Position != nil {
+		project.Position = *req.Position
+	} else {
+		// New projects go to the end of the list
+		projects, err := uc.projectRepo.GetByUserID(userID, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get projects: %w", err)
+		}
+		for _, existing := range projects {
+			if existing.Position >= project.Position {
+				project.Position = existing.Position + 1
+			}
+		}
+	}
+	if err := validateProject(project); err != nil {
+		return nil, err
+	}
+
+	createdProject, err := uc.projectRepo.Create(project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project: %w", err)
+	}
+
+	return createdProject, nil
+}
+
+func (uc *projectUseCase) Update(projectID, userID int, req *model.UpdateProjectRequest) (*entity.Project, error) {
+	project, err := uc.getOwned(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		project.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Color != nil {
+		project.Color = *req.Color
+	}
+	if req.Position != nil {
+		project.Position = *req.Position
+	}
+	if err := validateProject(project); err != nil {
+		return nil, err
+	}
+
+	updatedProject, err := uc.projectRepo.Update(project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update project: %w", err)
+	}
+
+	return updatedProject, nil
+}
+
+// Delete keeps the project's todos, outside of any project.
+func (uc *projectUseCase) Delete(projectID, userID int) error {
+	if _, err := uc.getOwned(projectID, userID); err != nil {
+		return err
+	}
+
+	if err := uc.projectRepo.Delete(projectID); err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+
+	return nil
+}
+
+func (uc *projectUseCase) Archive(projectID int, actor *entity.Actor, force bool) (*entity.Project, error) {
+	project, err := uc.getOwned(projectID, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if project.Archived {
+		return project, nil
+	}
+
+	if !force {
+		open, err := uc.projectRepo.CountOpenTodos(projectID)
+		if err != nil {
+			return nil, err
+		}
+		if open > 0 {
+			return nil, &ConflictError{
+				Message:   fmt.Sprintf("project has %d open todos; archive with force to cancel them", open),
+				OpenTodos: open,
+			}
+		}
+	}
+
+	var archivedProject *entity.Project
+	err = uc.transactor.WithinTransaction(func(tx repository.DBTX) error {
+		if err := archiveTodos(repository.NewTodoRepository(tx), repository.NewTodoRevisionRepository(tx), projectID, actor); err != nil {
+			return err
+		}
+
+		project.Archived = true
+		archivedProject, err = repository.NewProjectRepository(tx).Update(project)
+		if err != nil {
+			return fmt.Errorf("failed to archive project: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return archivedProject, nil
+}
+
+// archiveTodos archives the todos of a project, cancelling open ones first.
+// The status changes go through changeStatus, the transition check the todo
+// endpoints use, and each changed todo gets a revision. As when a todo is
+// cancelled through those endpoints, recurring todos get no next occurrence.
+func archiveTodos(todoRepo repository.TodoRepository, revisionRepo repository.TodoRevisionRepository, projectID int, actor *entity.Actor) error {
+	todos, err := todoRepo.GetByProjectID(projectID)
+	if err != nil {
+		return fmt.Errorf("failed to get todos: %w", err)
+	}
+
+	for _, todo := range todos {
+		before := *todo
+		if todo.Status.IsOpen() {
+			if err := changeStatus(todo, entity.TodoCancelled); err != nil {
+				return err
+			}
+		}
+		if todo.Status != entity.TodoArchived {
+			if err := changeStatus(todo, entity.TodoArchived); err != nil {
+				return err
+			}
+		}
+		changes := entity.DiffTodo(&before, todo)
+		if len(changes) == 0 {
+			continue
+		}
+
+		archivedTodo, err := todoRepo.Update(todo)
+		if err != nil {
+			if errors.Is(err, repository.ErrTodoVersionMismatch) {
+				return &ConflictError{Message: "a todo of the project changed while archiving; try again"}
+			}
+			return fmt.Errorf("failed to archive todo: %w", err)
+		}
+		if err := revisionRepo.Create(newTodoRevision(archivedTodo, actor, entity.TodoRevisionUpdate, changes)); err != nil {
+			return fmt.Errorf("failed to record archiving of todo %d: %w", archivedTodo.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Unarchive makes the project usable again. Its todos stay archived.
+func (uc *projectUseCase) Unarchive(projectID, userID int) (*entity.Project, error) {
+	project, err := uc.getOwned(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	project.Archived = false
+	updatedProject, err := uc.projectRepo.Update(project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unarchive project: %w", err)
+	}
+
+	return updatedProject, nil
+}
+
+func (uc *projectUseCase) getOwned(projectID, userID int) (*entity.Project, error) {
+	project, err := uc.projectRepo.GetByID(projectID)
+	if err != nil || project.UserID != userID {
+		return nil, fmt.Errorf("project not found")
+	}
+	return project, nil
+}
+
+func validateProject(project *entity.Project) error {
+	validationErr := &ValidationError{}
+
+	length := utf8.RuneCountInString(project.Name)
+	if length == 0 || length > maxProjectNameLength {
+		validationErr.Add("name", fmt.Sprintf("must be between 1 and %d characters", maxProjectNameLength))
+	}
+	if project.Color != "" && !colorPattern.MatchString(project.Color) {
+		validationErr.Add("color", "must be a hex color such as #1e90ff")
+	}
+	if project.Position < 0 {
+		validationErr.Add("position", "must not be negative")
+	}
+
+	return validationErr.ErrOrNil()
+}
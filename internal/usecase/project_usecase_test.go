@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+// fakeTodoRepository keeps the todos of one project in memory. Only the
+// methods used when archiving are implemented.
+type fakeTodoRepository struct {
+	repository.TodoRepository
+	todos []*entity.Todo
+}
+
+func (r *fakeTodoRepository) GetByProjectID(projectID int) ([]*entity.Todo, error) {
+	var todos []*entity.Todo
+	for _, todo := range r.todos {
+		copied := *todo
+		todos = append(todos, &copied)
+	}
+	return todos, nil
+}
+
+func (r *fakeTodoRepository) Update(todo *entity.Todo) (*entity.Todo, error) {
+	for i, stored := range r.todos {
+		if stored.ID == todo.ID {
+			if stored.Version != todo.Version {
+				return nil, repository.ErrTodoVersionMismatch
+			}
+			updated := *todo
+			updated.Version++
+			r.todos[i] = &updated
+			copied := updated
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrTodoVersionMismatch
+}
+
+type fakeTodoRevisionRepository struct {
+	repository.TodoRevisionRepository
+	revisions []*entity.TodoRevision
+}
+
+func (r *fakeTodoRevisionRepository) Create(revision *entity.TodoRevision) error {
+	r.revisions = append(r.revisions, revision)
+	return nil
+}
+
+func TestArchiveTodos(t *testing.T) {
+	completedAt := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	actor := &entity.Actor{UserID: 1}
+
+	tests := []struct {
+		name            string
+		todo            entity.Todo
+		wantChanges     []entity.FieldChange
+		wantCompletedAt bool
+	}{
+		{"pending", entity.Todo{Status: entity.TodoPending}, []entity.FieldChange{{Field: "status", From: "pending", To: "archived"}}, false},
+		{"in progress", entity.Todo{Status: entity.TodoInProgress}, []entity.FieldChange{{Field: "status", From: "in_progress", To: "archived"}}, false},
+		{"blocked", entity.Todo{Status: entity.TodoBlocked}, []entity.FieldChange{{Field: "status", From: "blocked", To: "archived"}}, false},
+		{"cancelled", entity.Todo{Status: entity.TodoCancelled}, []entity.FieldChange{{Field: "status", From: "cancelled", To: "archived"}}, false},
+		{"completed", entity.Todo{Status: entity.TodoCompleted, CompletedAt: &completedAt}, []entity.FieldChange{{Field: "status", From: "completed", To: "archived"}}, true},
+		{"already archived", entity.Todo{Status: entity.TodoArchived}, nil, false},
+		{"recurring", entity.Todo{Status: entity.TodoPending, Recurrence: "FREQ=DAILY"}, []entity.FieldChange{{Field: "status", From: "pending", To: "archived"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todo := tt.todo
+			todo.ID, todo.UserID, todo.Version = 7, 1, 3
+			todoRepo := &fakeTodoRepository{todos: []*entity.Todo{&todo}}
+			revisionRepo := &fakeTodoRevisionRepository{}
+
+			if err := archiveTodos(todoRepo, revisionRepo, 1, actor); err != nil {
+				t.Fatal(err)
+			}
+
+			stored := todoRepo.todos[0]
+			if stored.Status != entity.TodoArchived {
+				t.Errorf("status = %s, want archived", stored.Status)
+			}
+			if got := stored.CompletedAt != nil; got != tt.wantCompletedAt {
+				t.Errorf("completed_at set = %v, want %v", got, tt.wantCompletedAt)
+			}
+
+			if tt.wantChanges == nil {
+				if len(revisionRepo.revisions) != 0 || stored.Version != 3 {
+					t.Errorf("unchanged todo got %d revisions and version %d", len(revisionRepo.revisions), stored.Version)
+				}
+				return
+			}
+			if stored.Version != 4 {
+				t.Errorf("version = %d, want 4", stored.Version)
+			}
+			if len(revisionRepo.revisions) != 1 {
+				t.Fatalf("recorded %d revisions, want 1", len(revisionRepo.revisions))
+			}
+			if got := revisionRepo.revisions[0].Changes; !reflect.DeepEqual(got, tt.wantChanges) {
+				t.Errorf("changes = %v, want %v", got, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestArchiveTodosConflict(t *testing.T) {
+	todoRepo := &conflictingTodoRepository{fakeTodoRepository{todos: []*entity.Todo{{ID: 7, UserID: 1, Status: entity.TodoPending, Version: 3}}}}
+	err := archiveTodos(todoRepo, &fakeTodoRevisionRepository{}, 1, &entity.Actor{UserID: 1})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Errorf("archiveTodos() error = %v, want a conflict", err)
+	}
+}
+
+// conflictingTodoRepository changes every todo right after it is read.
+type conflictingTodoRepository struct {
+	fakeTodoRepository
+}
+
+func (r *conflictingTodoRepository) GetByProjectID(projectID int) ([]*entity.Todo, error) {
+	todos, err := r.fakeTodoRepository.GetByProjectID(projectID)
+	for _, todo := range r.todos {
+		todo.Version++
+	}
+	return todos, err
+}
@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+// permissionCacheTTL bounds how long a role change takes to reach other
+// replicas; changes made through this process are visible immediately.
+const permissionCacheTTL = 30 * time.Second
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+
+type RoleUseCase interface {
+	GetAll() ([]*entity.RoleDefinition, error)
+	Save(name entity.Role, req *model.SaveRoleRequest) (*entity.RoleDefinition, error)
+	Delete(name entity.Role) error
+	AssignRole(userID int, role entity.Role) (*entity.User, error)
+	PermissionsFor(role entity.Role) (entity.PermissionSet, error)
+}
+
+type cachedPermissions struct {
+	permissions entity.PermissionSet
+	expiresAt   time.Time
+}
+
+type roleUseCase struct {
+	roleRepo repository.RoleRepository
+	userRepo repository.UserRepository
+	users    *UserCache
+
+	mu    sync.Mutex
+	cache map[entity.Role]cachedPermissions
+}
+
+func NewRoleUseCase(roleRepo repository.RoleRepository, userRepo repository.UserRepository, users *UserCache) RoleUseCase {
+	return &roleUseCase{
+		roleRepo: roleRepo,
+		userRepo: userRepo,
+		users:    users,
+		cache:    make(map[entity.Role]cachedPermissions),
+	}
+}
+
+func (uc *roleUseCase) GetAll() ([]*entity.RoleDefinition, error) {
+	roles, err := uc.roleRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+func (uc *roleUseCase) Save(name entity.Role, req *model.SaveRoleRequest) (*entity.RoleDefinition, error) {
+	validationErr := &ValidationError{}
+	if !roleNamePattern.MatchString(string(name)) {
+		validationErr.Add("name", "must start with a lowercase letter and contain only lowercase letters, digits, '_' and '-'")
+	}
+	for _, permission := range req.Permissions {
+		if !entity.IsKnownPermission(permission) {
+			validationErr.Add("permissions", fmt.Sprintf("unknown permission %q", permission))
+		}
+	}
+	// Keep at least one role able to manage roles so nobody gets locked out
+	if name == entity.AdminRole && !entity.NewPermissionSet(req.Permissions...).Has(entity.PermissionRolesManage) {
+		validationErr.Add("permissions", "the admin role must keep roles:manage")
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	role, err := uc.roleRepo.Save(&entity.RoleDefinition{
+		Name:        name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save role: %w", err)
+	}
+
+	uc.invalidate(name)
+	return role, nil
+}
+
+func (uc *roleUseCase) Delete(name entity.Role) error {
+	if name.IsBuiltIn() {
+		return fmt.Errorf("built-in role %s cannot be deleted", name)
+	}
+
+	count, err := uc.userRepo.CountByRole(name)
+	if err != nil {
+		return fmt.Errorf("failed to check role usage: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("role %s is still assigned to %d users", name, count)
+	}
+
+	if err := uc.roleRepo.Delete(name); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	uc.invalidate(name)
+	return nil
+}
+
+func (uc *roleUseCase) AssignRole(userID int, role entity.Role) (*entity.User, error) {
+	if _, err := uc.roleRepo.GetByName(role); err != nil {
+		return nil, fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	// Tokens carry the role, so this also revokes the ones issued under the
+	// old role
+	updatedUser, err := uc.userRepo.SetRole(userID, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign role: %w", err)
+	}
+	uc.users.Invalidate(userID)
+
+	// Remove password from response
+	updatedUser.Password = ""
+	return updatedUser, nil
+}
+
+// PermissionsFor returns the permissions granted by role. Unknown roles get
+// an empty set rather than an error so stale tokens simply lose access.
+func (uc *roleUseCase) PermissionsFor(role entity.Role) (entity.PermissionSet, error) {
+	uc.mu.Lock()
+	cached, ok := uc.cache[role]
+	uc.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.permissions, nil
+	}
+
+	permissions := entity.NewPermissionSet()
+	definition, err := uc.roleRepo.GetByName(role)
+	if err == nil {
+		permissions = entity.NewPermissionSet(definition.Permissions...)
+	} else if !errors.Is(err, repository.ErrRoleNotFound) {
+		return nil, fmt.Errorf("failed to load role permissions: %w", err)
+	}
+
+	uc.mu.Lock()
+	uc.cache[role] = cachedPermissions{
+		permissions: permissions,
+		expiresAt:   time.Now().Add(permissionCacheTTL),
+	}
+	uc.mu.Unlock()
+
+	return permissions, nil
+}
+
+func (uc *roleUseCase) invalidate(role entity.Role) {
+	uc.mu.Lock()
+	delete(uc.cache, role)
+	uc.mu.Unlock()
+}
@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+type SessionUseCase interface {
+	GetByUserID(userID, currentSessionID int) ([]*entity.Session, error)
+	Revoke(userID, sessionID int) error
+}
+
+type sessionUseCase struct {
+	sessionRepo repository.SessionRepository
+}
+
+func NewSessionUseCase(sessionRepo repository.SessionRepository) SessionUseCase {
+	return &sessionUseCase{
+		sessionRepo: sessionRepo,
+	}
+}
+
+// GetByUserID lists the user's active sessions, flagging currentSessionID.
+func (uc *sessionUseCase) GetByUserID(userID, currentSessionID int) ([]*entity.Session, error) {
+	sessions, err := uc.sessionRepo.GetActiveByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+func (uc *sessionUseCase) Revoke(userID, sessionID int) error {
+	if err := uc.sessionRepo.Revoke(sessionID, userID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
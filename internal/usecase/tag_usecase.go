@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+// maxTagNameLength matches the tags.name column.
+const maxTagNameLength = 50
+
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+type TagUseCase interface {
+	GetByUserID(userID int) ([]*entity.Tag, error)
+	Create(userID int, req *model.SaveTagRequest) (*entity.Tag, error)
+	Update(tagID, userID int, req *model.SaveTagRequest) (*entity.Tag, error)
+	Delete(tagID, userID int) error
+}
+
+type tagUseCase struct {
+	tagRepo repository.TagRepository
+}
+
+func NewTagUseCase(tagRepo repository.TagRepository) TagUseCase {
+	return &tagUseCase{
+		tagRepo: tagRepo,
+	}
+}
+
+func (uc *tagUseCase) GetByUserID(userID int) ([]*entity.Tag, error) {
+	tags, err := uc.tagRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+func (uc *tagUseCase) Create(userID int, req *model.SaveTagRequest) (*entity.Tag, error) {
+	tag := &entity.Tag{
+		UserID: userID,
+		Name:   strings.TrimSpace(req.Name),
+		Color:  req.Color,
+	}
+	if err := uc.validate(tag); err != nil {
+		return nil, err
+	}
+
+	createdTag, err := uc.tagRepo.Create(tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return createdTag, nil
+}
+
+func (uc *tagUseCase) Update(tagID, userID int, req *model.SaveTagRequest) (*entity.Tag, error) {
+	tag, err := uc.getOwned(tagID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tag.Name = strings.TrimSpace(req.Name)
+	tag.Color = req.Color
+	if err := uc.validate(tag); err != nil {
+		return nil, err
+	}
+
+	updatedTag, err := uc.tagRepo.Update(tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	return updatedTag, nil
+}
+
+// Delete also detaches the tag from every todo.
+func (uc *tagUseCase) Delete(tagID, userID int) error {
+	if _, err := uc.getOwned(tagID, userID); err != nil {
+		return err
+	}
+
+	if err := uc.tagRepo.Delete(tagID); err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+
+	return nil
+}
+
+func (uc *tagUseCase) getOwned(tagID, userID int) (*entity.Tag, error) {
+	tag, err := uc.tagRepo.GetByID(tagID)
+	if err != nil || tag.UserID != userID {
+		return nil, fmt.Errorf("tag not found")
+	}
+	return tag, nil
+}
+
+func (uc *tagUseCase) validate(tag *entity.Tag) error {
+	validationErr := &ValidationError{}
+
+	length := utf8.RuneCountInString(tag.Name)
+	if length == 0 || length > maxTagNameLength {
+		validationErr.Add("name", fmt.Sprintf("must be between 1 and %d characters", maxTagNameLength))
+	}
+	// Listings filter by a comma-separated list of names
+	if strings.Contains(tag.Name, ",") {
+		validationErr.Add("name", "must not contain ','")
+	}
+	if tag.Color != "" && !colorPattern.MatchString(tag.Color) {
+		validationErr.Add("color", "must be a hex color such as #1e90ff")
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		return err
+	}
+
+	existing, err := uc.tagRepo.GetByUserIDAndName(tag.UserID, tag.Name)
+	if err == nil && existing.ID != tag.ID {
+		return fmt.Errorf("tag %q already exists", tag.Name)
+	}
+
+	return nil
+}
@@ -1,29 +1,89 @@
 package usecase
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
+	"github.com/islamyakin/otel-propagation-monorepo/internal/config"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/model"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/rrule"
 )
 
+// maxOccurrencePreview caps how many due dates Occurrences returns.
+const maxOccurrencePreview = 100
+
+const (
+	maxSearchQueryLength = 200
+	maxSearchResults     = 100
+)
+
+// maxBulkOperations caps the operations of one bulk request.
+const maxBulkOperations = 100
+
 type TodoUseCase interface {
 	Create(userID int, req *model.CreateTodoRequest) (*entity.Todo, error)
-	GetByUserID(userID int) ([]*entity.Todo, error)
-	GetAll() ([]*entity.Todo, error) // Admin only
-	GetByID(todoID, userID int, isAdmin bool) (*entity.Todo, error)
-	Update(todoID, userID int, req *model.UpdateTodoRequest, isAdmin bool) (*entity.Todo, error)
-	Delete(todoID, userID int, isAdmin bool) error
+	GetByUserID(userID int, filter *model.TodoFilter) ([]*entity.Todo, error)
+	GetAll(actor *entity.Actor) ([]*entity.Todo, error)
+	Search(userID int, query string, limit int) ([]*entity.TodoSearchResult, error)
+	// SearchAll searches the todos of every user.
+	SearchAll(actor *entity.Actor, query string, limit int) ([]*entity.TodoSearchResult, error)
+	GetByID(todoID int, actor *entity.Actor) (*entity.Todo, error)
+	// Update changes the todo when req.IfMatch accepts its version. Without
+	// If-Match the request fails when config.TodoConfig.RequireIfMatch is set.
+	Update(todoID int, actor *entity.Actor, req *model.UpdateTodoRequest) (*entity.Todo, error)
+	// Delete moves the todo and its subtasks to the trash. Like Update, it
+	// checks the If-Match header against the todo's version.
+	Delete(todoID int, actor *entity.Actor, ifMatch *model.IfMatch) error
+	GetTrash(userID int) ([]*entity.Todo, error)
+	Restore(todoID int, actor *entity.Actor) (*entity.Todo, error)
+	// PurgeTrash permanently removes todos that have been in the trash for
+	// longer than retention and returns how many were removed.
+	PurgeTrash(retention time.Duration) (int64, error)
+	AttachTag(todoID int, actor *entity.Actor, req *model.AttachTagRequest) (*entity.Todo, error)
+	DetachTag(todoID int, actor *entity.Actor, tagID int) (*entity.Todo, error)
+	// Occurrences previews up to count due dates of a recurring todo,
+	// starting with its own.
+	Occurrences(todoID int, actor *entity.Actor, count int) (*model.OccurrencesResponse, error)
+	// Bulk applies a list of operations with the same checks as the single
+	// todo endpoints and reports the outcome of each.
+	Bulk(actor *entity.Actor, req *model.BulkTodoRequest) ([]*model.BulkTodoResult, error)
+	// GetHistory lists the recorded changes of a todo, oldest first. Todos
+	// in the trash keep their history.
+	GetHistory(todoID int, actor *entity.Actor) ([]*entity.TodoRevision, error)
 }
 
 type todoUseCase struct {
-	todoRepo repository.TodoRepository
+	todoRepo     repository.TodoRepository
+	tagRepo      repository.TagRepository
+	projectRepo  repository.ProjectRepository
+	revisionRepo repository.TodoRevisionRepository
+	transactor   repository.Transactor
+	config       *config.Config
+	// inTransaction is set on the copies withTransaction binds to a
+	// transaction
+	inTransaction bool
 }
 
-func NewTodoUseCase(todoRepo repository.TodoRepository) TodoUseCase {
+func NewTodoUseCase(
+	todoRepo repository.TodoRepository,
+	tagRepo repository.TagRepository,
+	projectRepo repository.ProjectRepository,
+	revisionRepo repository.TodoRevisionRepository,
+	transactor repository.Transactor,
+	config *config.Config,
+) TodoUseCase {
 	return &todoUseCase{
-		todoRepo: todoRepo,
+		todoRepo:     todoRepo,
+		tagRepo:      tagRepo,
+		projectRepo:  projectRepo,
+		revisionRepo: revisionRepo,
+		transactor:   transactor,
+		config:       config,
 	}
 }
 
@@ -33,6 +93,21 @@ func (uc *todoUseCase) Create(userID int, req *model.CreateTodoRequest) (*entity
 		Title:       req.Title,
 		Description: req.Description,
 		Status:      entity.TodoPending,
+		Occurrence:  1,
+	}
+	if req.ProjectID != nil {
+		if err := uc.checkProject(*req.ProjectID, userID); err != nil {
+			return nil, err
+		}
+		todo.ProjectID = req.ProjectID
+	}
+	if req.ParentID != nil {
+		if err := uc.placeSubtask(todo, *req.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	if err := setSchedule(todo, req.DueAt, req.Timezone, req.Recurrence); err != nil {
+		return nil, err
 	}
 
 	createdTodo, err := uc.todoRepo.Create(todo)
@@ -43,8 +118,14 @@ func (uc *todoUseCase) Create(userID int, req *model.CreateTodoRequest) (*entity
 	return createdTodo, nil
 }
 
-func (uc *todoUseCase) GetByUserID(userID int) ([]*entity.Todo, error) {
-	todos, err := uc.todoRepo.GetByUserID(userID)
+func (uc *todoUseCase) GetByUserID(userID int, filter *model.TodoFilter) ([]*entity.Todo, error) {
+	var todos []*entity.Todo
+	var err error
+	if filter != nil && len(filter.Tags) > 0 {
+		todos, err = uc.todoRepo.GetByUserIDAndTags(userID, filter.Tags, filter.MatchAll)
+	} else {
+		todos, err = uc.todoRepo.GetByUserID(userID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get todos: %w", err)
 	}
@@ -52,7 +133,11 @@ func (uc *todoUseCase) GetByUserID(userID int) ([]*entity.Todo, error) {
 	return todos, nil
 }
 
-func (uc *todoUseCase) GetAll() ([]*entity.Todo, error) {
+func (uc *todoUseCase) GetAll(actor *entity.Actor) ([]*entity.Todo, error) {
+	if !actor.Can(entity.PermissionTodosReadAny) {
+		return nil, &ForbiddenError{Permission: entity.PermissionTodosReadAny}
+	}
+
 	todos, err := uc.todoRepo.GetAll()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all todos: %w", err)
@@ -61,40 +146,85 @@ func (uc *todoUseCase) GetAll() ([]*entity.Todo, error) {
 	return todos, nil
 }
 
-func (uc *todoUseCase) GetByID(todoID, userID int, isAdmin bool) (*entity.Todo, error) {
-	var todo *entity.Todo
-	var err error
+func (uc *todoUseCase) Search(userID int, query string, limit int) ([]*entity.TodoSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if err := validateSearch(query, limit); err != nil {
+		return nil, err
+	}
 
-	if isAdmin {
-		// Admin can see any todo
-		todo, err = uc.todoRepo.GetByID(todoID)
-	} else {
-		// User can only see their own todo
-		todo, err = uc.todoRepo.GetByIDAndUserID(todoID, userID)
+	results, err := uc.todoRepo.Search(userID, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search todos: %w", err)
 	}
 
+	return results, nil
+}
+
+func (uc *todoUseCase) SearchAll(actor *entity.Actor, query string, limit int) ([]*entity.TodoSearchResult, error) {
+	if !actor.Can(entity.PermissionTodosReadAny) {
+		return nil, &ForbiddenError{Permission: entity.PermissionTodosReadAny}
+	}
+
+	query = strings.TrimSpace(query)
+	if err := validateSearch(query, limit); err != nil {
+		return nil, err
+	}
+
+	results, err := uc.todoRepo.SearchAll(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search todos: %w", err)
+	}
+
+	return results, nil
+}
+
+// GetByID also loads the todo's subtasks and, when it has any, their
+// progress.
+func (uc *todoUseCase) GetByID(todoID int, actor *entity.Actor) (*entity.Todo, error) {
+	todo, err := uc.getForActor(todoID, actor, entity.PermissionTodosReadAny, entity.PermissionTodosReadOwn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get todo: %w", err)
 	}
 
+	subtasks, err := uc.todoRepo.GetByParentID(todo.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subtasks: %w", err)
+	}
+	if len(subtasks) > 0 {
+		progress := entity.SummarizeProgress(subtasks)
+		todo.Subtasks = subtasks
+		todo.Progress = &progress
+	}
+
 	return todo, nil
 }
 
-func (uc *todoUseCase) Update(todoID, userID int, req *model.UpdateTodoRequest, isAdmin bool) (*entity.Todo, error) {
-	var existingTodo *entity.Todo
-	var err error
-
-	if isAdmin {
-		// Admin can update any todo
-		existingTodo, err = uc.todoRepo.GetByID(todoID)
-	} else {
-		// User can only update their own todo
-		existingTodo, err = uc.todoRepo.GetByIDAndUserID(todoID, userID)
+// Update runs in one transaction, so completing a recurring todo and creating
+// its next occurrence, or moving a todo and its subtasks, happen together or
+// not at all.
+func (uc *todoUseCase) Update(todoID int, actor *entity.Actor, req *model.UpdateTodoRequest) (*entity.Todo, error) {
+	var updatedTodo *entity.Todo
+	err := uc.withTransaction(func(txUseCase *todoUseCase) error {
+		var err error
+		updatedTodo, err = txUseCase.update(todoID, actor, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	return updatedTodo, nil
+}
+
+func (uc *todoUseCase) update(todoID int, actor *entity.Actor, req *model.UpdateTodoRequest) (*entity.Todo, error) {
+	existingTodo, err := uc.getForActor(todoID, actor, entity.PermissionTodosWriteAny, entity.PermissionTodosWriteOwn)
 	if err != nil {
 		return nil, fmt.Errorf("todo not found or access denied: %w", err)
 	}
+	if err := uc.checkVersion(existingTodo, req.IfMatch); err != nil {
+		return nil, err
+	}
+	before := *existingTodo
 
 	// Update fields if provided
 	if req.Title != nil {
@@ -103,38 +233,727 @@ func (uc *todoUseCase) Update(todoID, userID int, req *model.UpdateTodoRequest,
 	if req.Description != nil {
 		existingTodo.Description = *req.Description
 	}
+	if req.DueAt != nil || req.Timezone != nil || req.Recurrence != nil {
+		dueAt, timezone, recurrence := existingTodo.DueAt, existingTodo.Timezone, existingTodo.Recurrence
+		if req.DueAt != nil {
+			dueAt = req.DueAt
+		}
+		if req.Timezone != nil {
+			timezone = *req.Timezone
+		}
+		if req.Recurrence != nil {
+			recurrence = *req.Recurrence
+		}
+		if err := setSchedule(existingTodo, dueAt, timezone, recurrence); err != nil {
+			return nil, err
+		}
+	}
+	completing := false
 	if req.Status != nil {
-		existingTodo.Status = *req.Status
+		completing = *req.Status == entity.TodoCompleted && existingTodo.Status != entity.TodoCompleted
+		if *req.Status == entity.TodoCompleted && !req.Force {
+			if err := uc.checkSubtasksDone(existingTodo); err != nil {
+				return nil, err
+			}
+		}
+		if err := changeStatus(existingTodo, *req.Status); err != nil {
+			return nil, err
+		}
+	}
+	if req.Position != nil {
+		if *req.Position < 0 {
+			validationErr := &ValidationError{}
+			validationErr.Add("position", "must not be negative")
+			return nil, validationErr
+		}
+		existingTodo.Position = *req.Position
+	}
+	projectChanged := false
+	if req.ProjectID != nil {
+		if existingTodo.ParentID != nil {
+			validationErr := &ValidationError{}
+			validationErr.Add("project_id", "subtasks stay in their parent's project")
+			return nil, validationErr
+		}
+		projectChanged = true
+		if *req.ProjectID == 0 {
+			existingTodo.ProjectID = nil
+		} else {
+			if err := uc.checkProject(*req.ProjectID, existingTodo.UserID); err != nil {
+				return nil, err
+			}
+			existingTodo.ProjectID = req.ProjectID
+		}
+	}
+
+	// Completing a recurring todo hands the series on to a new todo
+	var next *entity.Todo
+	if completing && existingTodo.Recurrence != "" {
+		next, err = nextOccurrence(existingTodo)
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			existingTodo.Recurrence = ""
+		}
+	}
+
+	// Nothing to store, so neither a new version nor a revision
+	changes := entity.DiffTodo(&before, existingTodo)
+	if len(changes) == 0 {
+		return existingTodo, nil
 	}
 
 	updatedTodo, err := uc.todoRepo.Update(existingTodo)
 	if err != nil {
+		if errors.Is(err, repository.ErrTodoVersionMismatch) {
+			return nil, &PreconditionFailedError{}
+		}
 		return nil, fmt.Errorf("failed to update todo: %w", err)
 	}
+	if err := uc.recordRevision(updatedTodo, actor, entity.TodoRevisionUpdate, changes); err != nil {
+		return nil, err
+	}
+
+	if next != nil {
+		updatedTodo.NextOccurrence, err = uc.createOccurrence(next, existingTodo.Tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Subtasks follow their parent into the new project
+	if projectChanged {
+		subtasks, err := uc.todoRepo.GetByParentID(updatedTodo.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get subtasks: %w", err)
+		}
+		for _, subtask := range subtasks {
+			before := *subtask
+			subtask.ProjectID = updatedTodo.ProjectID
+			changes := entity.DiffTodo(&before, subtask)
+			if len(changes) == 0 {
+				continue
+			}
+			movedSubtask, err := uc.todoRepo.Update(subtask)
+			if err != nil {
+				return nil, fmt.Errorf("failed to move subtask: %w", err)
+			}
+			if err := uc.recordRevision(movedSubtask, actor, entity.TodoRevisionUpdate, changes); err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	return updatedTodo, nil
 }
 
-func (uc *todoUseCase) Delete(todoID, userID int, isAdmin bool) error {
-	var err error
+func (uc *todoUseCase) Delete(todoID int, actor *entity.Actor, ifMatch *model.IfMatch) error {
+	todo, err := uc.getForActor(todoID, actor, entity.PermissionTodosWriteAny, entity.PermissionTodosWriteOwn)
+	if err != nil {
+		return fmt.Errorf("todo not found or access denied: %w", err)
+	}
+	if err := uc.checkVersion(todo, ifMatch); err != nil {
+		return err
+	}
 
-	if isAdmin {
-		// Admin can delete any todo
-		err = uc.todoRepo.Delete(todoID)
-	} else {
-		// User can only delete their own todo
-		// First check if todo belongs to user
-		_, err = uc.todoRepo.GetByIDAndUserID(todoID, userID)
+	return uc.withTransaction(func(tx *todoUseCase) error {
+		subtaskIDs, err := tx.todoRepo.Delete(todo.ID, todo.Version)
+		if err != nil {
+			if errors.Is(err, repository.ErrTodoVersionMismatch) {
+				return &PreconditionFailedError{}
+			}
+			return fmt.Errorf("failed to delete todo: %w", err)
+		}
+
+		return tx.recordCascade(todo, subtaskIDs, actor, entity.TodoRevisionDelete)
+	})
+}
+
+// recordCascade records action for todo and for the subtasks that went along
+// with it, which belong to the same user.
+func (uc *todoUseCase) recordCascade(todo *entity.Todo, subtaskIDs []int, actor *entity.Actor, action entity.TodoRevisionAction) error {
+	if err := uc.recordRevision(todo, actor, action, []entity.FieldChange{}); err != nil {
+		return err
+	}
+	for _, subtaskID := range subtaskIDs {
+		subtask := &entity.Todo{ID: subtaskID, UserID: todo.UserID}
+		if err := uc.recordRevision(subtask, actor, action, []entity.FieldChange{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (uc *todoUseCase) GetTrash(userID int) ([]*entity.Todo, error) {
+	todos, err := uc.todoRepo.GetDeletedByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trash: %w", err)
+	}
+
+	return todos, nil
+}
+
+// Restore brings a todo back from the trash. A subtask needs its parent
+// restored first, and a todo of an archived project needs the project
+// unarchived.
+func (uc *todoUseCase) Restore(todoID int, actor *entity.Actor) (*entity.Todo, error) {
+	if !actor.Can(entity.PermissionTodosWriteAny) && !actor.Can(entity.PermissionTodosWriteOwn) {
+		return nil, &ForbiddenError{Permission: entity.PermissionTodosWriteOwn}
+	}
+
+	todo, err := uc.todoRepo.GetDeletedByID(todoID)
+	if err != nil || (!actor.Can(entity.PermissionTodosWriteAny) && todo.UserID != actor.UserID) {
+		return nil, fmt.Errorf("todo not found in trash")
+	}
+
+	validationErr := &ValidationError{}
+	if todo.ParentID != nil {
+		if _, err := uc.todoRepo.GetByID(*todo.ParentID); err != nil {
+			validationErr.Add("parent_id", "parent todo is in the trash; restore it first")
+		}
+	}
+	if todo.ProjectID != nil {
+		if project, err := uc.projectRepo.GetByID(*todo.ProjectID); err == nil && project.Archived {
+			validationErr.Add("project_id", "project is archived; unarchive it first")
+		}
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	var restoredTodo *entity.Todo
+	err = uc.withTransaction(func(tx *todoUseCase) error {
+		var subtaskIDs []int
+		restoredTodo, subtaskIDs, err = tx.todoRepo.Restore(todo.ID)
 		if err != nil {
-			return fmt.Errorf("todo not found or access denied: %w", err)
+			return fmt.Errorf("failed to restore todo: %w", err)
 		}
 
-		err = uc.todoRepo.Delete(todoID)
+		return tx.recordCascade(restoredTodo, subtaskIDs, actor, entity.TodoRevisionRestore)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restoredTodo, nil
+}
+
+func (uc *todoUseCase) PurgeTrash(retention time.Duration) (int64, error) {
+	purged, err := uc.todoRepo.Purge(time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+
+	return purged, nil
+}
+
+// AttachTag labels the todo with one of its owner's tags.
+func (uc *todoUseCase) AttachTag(todoID int, actor *entity.Actor, req *model.AttachTagRequest) (*entity.Todo, error) {
+	todo, err := uc.getForActor(todoID, actor, entity.PermissionTodosWriteAny, entity.PermissionTodosWriteOwn)
+	if err != nil {
+		return nil, fmt.Errorf("todo not found or access denied: %w", err)
+	}
+
+	tag, err := uc.tagRepo.GetByID(req.TagID)
+	if err != nil || tag.UserID != todo.UserID {
+		return nil, fmt.Errorf("tag not found")
 	}
 
+	return uc.changeTags(todo.ID, actor, func(todoRepo repository.TodoRepository) error {
+		return todoRepo.AttachTag(todo.ID, tag.ID)
+	})
+}
+
+func (uc *todoUseCase) DetachTag(todoID int, actor *entity.Actor, tagID int) (*entity.Todo, error) {
+	todo, err := uc.getForActor(todoID, actor, entity.PermissionTodosWriteAny, entity.PermissionTodosWriteOwn)
+	if err != nil {
+		return nil, fmt.Errorf("todo not found or access denied: %w", err)
+	}
+
+	return uc.changeTags(todo.ID, actor, func(todoRepo repository.TodoRepository) error {
+		return todoRepo.DetachTag(todo.ID, tagID)
+	})
+}
+
+// changeTags applies change to the tags of a todo. Tags are part of the
+// todo's representation, so a change gives it a new version and a revision,
+// written in the same transaction; attaching a tag twice stores nothing.
+func (uc *todoUseCase) changeTags(todoID int, actor *entity.Actor, change func(todoRepo repository.TodoRepository) error) (*entity.Todo, error) {
+	var updatedTodo *entity.Todo
+	err := uc.withTransaction(func(tx *todoUseCase) error {
+		before, err := tx.todoRepo.GetByID(todoID)
+		if err != nil {
+			return err
+		}
+		if err := change(tx.todoRepo); err != nil {
+			return err
+		}
+		after, err := tx.todoRepo.GetByID(todoID)
+		if err != nil {
+			return err
+		}
+
+		changes := entity.DiffTodo(before, after)
+		if len(changes) == 0 {
+			updatedTodo = after
+			return nil
+		}
+
+		// Checked against the version read before the change, so a
+		// concurrent update is not overwritten
+		after.Version = before.Version
+		updatedTodo, err = tx.todoRepo.Update(after)
+		if err != nil {
+			if errors.Is(err, repository.ErrTodoVersionMismatch) {
+				return &PreconditionFailedError{}
+			}
+			return fmt.Errorf("failed to update todo: %w", err)
+		}
+		return tx.recordRevision(updatedTodo, actor, entity.TodoRevisionUpdate, changes)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to delete todo: %w", err)
+		return nil, err
 	}
 
+	return updatedTodo, nil
+}
+
+func (uc *todoUseCase) Occurrences(todoID int, actor *entity.Actor, count int) (*model.OccurrencesResponse, error) {
+	if count < 1 || count > maxOccurrencePreview {
+		validationErr := &ValidationError{}
+		validationErr.Add("count", fmt.Sprintf("must be between 1 and %d", maxOccurrencePreview))
+		return nil, validationErr
+	}
+
+	todo, err := uc.getForActor(todoID, actor, entity.PermissionTodosReadAny, entity.PermissionTodosReadOwn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todo: %w", err)
+	}
+	if todo.Recurrence == "" {
+		validationErr := &ValidationError{}
+		validationErr.Add("recurrence", "todo does not recur")
+		return nil, validationErr
+	}
+
+	rule, err := rrule.Parse(todo.Recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored recurrence: %w", err)
+	}
+	location, err := time.LoadLocation(todo.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored timezone: %w", err)
+	}
+
+	return &model.OccurrencesResponse{
+		Recurrence:  todo.Recurrence,
+		Timezone:    todo.Timezone,
+		Occurrences: rule.Occurrences(todo.DueAt.In(location), todo.Occurrence, count),
+	}, nil
+}
+
+func (uc *todoUseCase) Bulk(actor *entity.Actor, req *model.BulkTodoRequest) ([]*model.BulkTodoResult, error) {
+	if n := len(req.Operations); n == 0 || n > maxBulkOperations {
+		validationErr := &ValidationError{}
+		validationErr.Add("operations", fmt.Sprintf("must hold between 1 and %d operations", maxBulkOperations))
+		return nil, validationErr
+	}
+
+	results := make([]*model.BulkTodoResult, len(req.Operations))
+	for i, op := range req.Operations {
+		results[i] = &model.BulkTodoResult{Index: i, Op: op.Op}
+	}
+
+	// Without atomic each operation still gets a transaction of its own
+	if !req.Atomic {
+		for i := range req.Operations {
+			result := results[i]
+			err := uc.withTransaction(func(txUseCase *todoUseCase) error {
+				result.Todo, result.Err = txUseCase.applyBulkOperation(actor, &req.Operations[i])
+				return result.Err
+			})
+			if err != nil && result.Err == nil {
+				result.Todo, result.Err = nil, fmt.Errorf("failed to apply operation: %w", err)
+			}
+		}
+		return results, nil
+	}
+
+	failed := false
+	err := uc.withTransaction(func(txUseCase *todoUseCase) error {
+		for i := range req.Operations {
+			todo, err := txUseCase.applyBulkOperation(actor, &req.Operations[i])
+			if err != nil {
+				results[i].Err = err
+				failed = true
+				return err
+			}
+			results[i].Todo = todo
+		}
+		return nil
+	})
+	if err != nil && !failed {
+		// Every operation worked but the commit did not
+		return nil, fmt.Errorf("failed to apply bulk operations: %w", err)
+	}
+	if failed {
+		for _, result := range results {
+			if result.Err == nil {
+				result.Todo = nil
+				result.Err = ErrRolledBack
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// applyBulkOperation runs one bulk operation through the single todo methods.
+// Bulk requests only reach the actor's own todos, whatever other permissions
+// they hold. Todos to create have passed the request binding already.
+func (uc *todoUseCase) applyBulkOperation(actor *entity.Actor, op *model.BulkTodoOperation) (*entity.Todo, error) {
+	var ifMatch *model.IfMatch
+	if op.Version != nil {
+		ifMatch = &model.IfMatch{Versions: []int{*op.Version}}
+	}
+
+	validationErr := &ValidationError{}
+	switch op.Op {
+	case model.BulkCreate:
+		if !actor.Can(entity.PermissionTodosWriteOwn) {
+			return nil, &ForbiddenError{Permission: entity.PermissionTodosWriteOwn}
+		}
+		if op.Todo == nil {
+			validationErr.Add("todo", "is required")
+			return nil, validationErr
+		}
+		return uc.Create(actor.UserID, op.Todo)
+	case model.BulkUpdateStatus:
+		if op.Status == nil {
+			validationErr.Add("status", "is required")
+			return nil, validationErr
+		}
+		if err := uc.checkOwned(op.ID, actor); err != nil {
+			return nil, err
+		}
+		return uc.Update(op.ID, actor, &model.UpdateTodoRequest{Status: op.Status, Force: op.Force, IfMatch: ifMatch})
+	case model.BulkMove:
+		if op.ProjectID == nil {
+			validationErr.Add("project_id", "is required")
+			return nil, validationErr
+		}
+		if err := uc.checkOwned(op.ID, actor); err != nil {
+			return nil, err
+		}
+		return uc.Update(op.ID, actor, &model.UpdateTodoRequest{ProjectID: op.ProjectID, IfMatch: ifMatch})
+	case model.BulkDelete:
+		if err := uc.checkOwned(op.ID, actor); err != nil {
+			return nil, err
+		}
+		return nil, uc.Delete(op.ID, actor, ifMatch)
+	default:
+		validationErr.Add("op", fmt.Sprintf("must be one of %s, %s, %s, %s", model.BulkCreate, model.BulkUpdateStatus, model.BulkMove, model.BulkDelete))
+		return nil, validationErr
+	}
+}
+
+// checkOwned makes sure the todo belongs to the actor.
+func (uc *todoUseCase) checkOwned(todoID int, actor *entity.Actor) error {
+	if _, err := uc.todoRepo.GetByIDAndUserID(todoID, actor.UserID); err != nil {
+		return fmt.Errorf("todo not found or access denied: %w", err)
+	}
+	return nil
+}
+
+// withTransaction runs fn with a copy of the use case whose todo and revision
+// repositories work inside one transaction. Called on such a copy, fn joins
+// the transaction already open.
+func (uc *todoUseCase) withTransaction(fn func(txUseCase *todoUseCase) error) error {
+	if uc.inTransaction {
+		return fn(uc)
+	}
+
+	return uc.transactor.WithinTransaction(func(tx repository.DBTX) error {
+		txUseCase := *uc
+		txUseCase.todoRepo = repository.NewTodoRepository(tx)
+		txUseCase.revisionRepo = repository.NewTodoRevisionRepository(tx)
+		txUseCase.inTransaction = true
+		return fn(&txUseCase)
+	})
+}
+
+func (uc *todoUseCase) GetHistory(todoID int, actor *entity.Actor) ([]*entity.TodoRevision, error) {
+	if !actor.Can(entity.PermissionTodosReadAny) && !actor.Can(entity.PermissionTodosReadOwn) {
+		return nil, &ForbiddenError{Permission: entity.PermissionTodosReadOwn}
+	}
+
+	todo, err := uc.todoRepo.GetByID(todoID)
+	if err != nil {
+		todo, err = uc.todoRepo.GetDeletedByID(todoID)
+	}
+	if err != nil || (!actor.Can(entity.PermissionTodosReadAny) && todo.UserID != actor.UserID) {
+		return nil, fmt.Errorf("failed to get todo: todo not found")
+	}
+
+	revisions, err := uc.revisionRepo.GetByTodoID(todo.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todo history: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// checkVersion makes sure a change is based on the todo's current version.
+// Without an If-Match header the version read just before is used, which
+// still catches changes racing with this one.
+func (uc *todoUseCase) checkVersion(todo *entity.Todo, ifMatch *model.IfMatch) error {
+	if ifMatch == nil {
+		if uc.config.Todo.RequireIfMatch {
+			return ErrPreconditionRequired
+		}
+		return nil
+	}
+	if !ifMatch.Matches(todo.Version) {
+		return &PreconditionFailedError{Version: todo.Version}
+	}
 	return nil
 }
+
+// recordRevision stores who changed the todo and how. Inside a transaction
+// a failure aborts it, so it is returned and the change rolled back with it.
+// Otherwise the change has already been made, so a failure is only logged.
+func (uc *todoUseCase) recordRevision(todo *entity.Todo, actor *entity.Actor, action entity.TodoRevisionAction, changes []entity.FieldChange) error {
+	err := uc.revisionRepo.Create(newTodoRevision(todo, actor, action, changes))
+	if err == nil {
+		return nil
+	}
+	if uc.inTransaction {
+		return fmt.Errorf("failed to record %s of todo %d: %w", action, todo.ID, err)
+	}
+	log.Printf("failed to record %s of todo %d: %v", action, todo.ID, err)
+	return nil
+}
+
+func newTodoRevision(todo *entity.Todo, actor *entity.Actor, action entity.TodoRevisionAction, changes []entity.FieldChange) *entity.TodoRevision {
+	return &entity.TodoRevision{
+		TodoID:         todo.ID,
+		ActorUserID:    actor.UserID,
+		ImpersonatorID: actor.ImpersonatorID,
+		AdminAction:    actor.UserID != todo.UserID || actor.IsImpersonated(),
+		Action:         action,
+		Changes:        changes,
+	}
+}
+
+// createOccurrence stores the next todo of a series and gives it the tags of
+// the previous one.
+func (uc *todoUseCase) createOccurrence(next *entity.Todo, tags []entity.Tag) (*entity.Todo, error) {
+	createdTodo, err := uc.todoRepo.Create(next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create next occurrence: %w", err)
+	}
+
+	for _, tag := range tags {
+		if err := uc.todoRepo.AttachTag(createdTodo.ID, tag.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return uc.todoRepo.GetByID(createdTodo.ID)
+}
+
+// placeSubtask puts todo under parentID, at the end of its subtasks. Only one
+// level of nesting is allowed.
+func (uc *todoUseCase) placeSubtask(todo *entity.Todo, parentID int) error {
+	validationErr := &ValidationError{}
+
+	parent, err := uc.todoRepo.GetByIDAndUserID(parentID, todo.UserID)
+	if err != nil {
+		validationErr.Add("parent_id", "todo not found")
+		return validationErr
+	}
+	if parent.ParentID != nil {
+		validationErr.Add("parent_id", "subtasks cannot have subtasks")
+	}
+	if !parent.Status.IsOpen() {
+		validationErr.Add("parent_id", fmt.Sprintf("parent todo is %s", parent.Status))
+	}
+	if todo.ProjectID != nil && (parent.ProjectID == nil || *todo.ProjectID != *parent.ProjectID) {
+		validationErr.Add("project_id", "subtasks stay in their parent's project")
+	}
+	if err := validationErr.ErrOrNil(); err != nil {
+		return err
+	}
+
+	siblings, err := uc.todoRepo.GetByParentID(parent.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get subtasks: %w", err)
+	}
+	for _, sibling := range siblings {
+		if sibling.Position >= todo.Position {
+			todo.Position = sibling.Position + 1
+		}
+	}
+
+	todo.ParentID = &parent.ID
+	todo.ProjectID = parent.ProjectID
+	return nil
+}
+
+// checkSubtasksDone refuses to complete a todo while subtasks are open.
+func (uc *todoUseCase) checkSubtasksDone(todo *entity.Todo) error {
+	subtasks, err := uc.todoRepo.GetByParentID(todo.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get subtasks: %w", err)
+	}
+
+	open := 0
+	for _, subtask := range subtasks {
+		if subtask.Status.IsOpen() {
+			open++
+		}
+	}
+	if open > 0 {
+		return &ConflictError{
+			Message:   fmt.Sprintf("todo has %d open subtasks; complete it with force to proceed", open),
+			OpenTodos: open,
+		}
+	}
+
+	return nil
+}
+
+// checkProject makes sure todos of userID can be put into the project.
+func (uc *todoUseCase) checkProject(projectID, userID int) error {
+	project, err := uc.projectRepo.GetByID(projectID)
+	if err != nil || project.UserID != userID {
+		validationErr := &ValidationError{}
+		validationErr.Add("project_id", "project not found")
+		return validationErr
+	}
+	if project.Archived {
+		validationErr := &ValidationError{}
+		validationErr.Add("project_id", "project is archived")
+		return validationErr
+	}
+	return nil
+}
+
+func validateSearch(query string, limit int) error {
+	validationErr := &ValidationError{}
+	if query == "" {
+		validationErr.Add("q", "is required")
+	} else if len(query) > maxSearchQueryLength {
+		validationErr.Add("q", fmt.Sprintf("must be at most %d characters", maxSearchQueryLength))
+	}
+	if limit < 1 || limit > maxSearchResults {
+		validationErr.Add("limit", fmt.Sprintf("must be between 1 and %d", maxSearchResults))
+	}
+	return validationErr.ErrOrNil()
+}
+
+// setSchedule validates and applies a due date, timezone and recurrence.
+// Recurrences are stored in canonical form and need a due date to count from.
+func setSchedule(todo *entity.Todo, dueAt *time.Time, timezone, recurrence string) error {
+	validationErr := &ValidationError{}
+
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "Local" {
+		validationErr.Add("timezone", "must be an IANA time zone such as Europe/Berlin")
+	}
+
+	if recurrence != "" {
+		rule, err := rrule.Parse(recurrence)
+		switch {
+		case err != nil:
+			validationErr.Add("recurrence", err.Error())
+		case todo.ParentID != nil:
+			validationErr.Add("recurrence", "subtasks cannot recur")
+		case dueAt == nil:
+			validationErr.Add("due_at", "is required for a recurring todo")
+		default:
+			recurrence = rule.String()
+		}
+	}
+
+	if err := validationErr.ErrOrNil(); err != nil {
+		return err
+	}
+
+	todo.DueAt = dueAt
+	todo.Timezone = timezone
+	todo.Recurrence = recurrence
+	return nil
+}
+
+// nextOccurrence builds the todo following a recurring one, due at the next
+// date of its rule in its timezone. It returns nil when the series has ended.
+func nextOccurrence(todo *entity.Todo) (*entity.Todo, error) {
+	rule, err := rrule.Parse(todo.Recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored recurrence: %w", err)
+	}
+	location, err := time.LoadLocation(todo.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored timezone: %w", err)
+	}
+
+	dueAt, ok := rule.Next(todo.DueAt.In(location), todo.Occurrence)
+	if !ok {
+		return nil, nil
+	}
+
+	return &entity.Todo{
+		UserID:      todo.UserID,
+		ProjectID:   todo.ProjectID,
+		Title:       todo.Title,
+		Description: todo.Description,
+		Status:      entity.TodoPending,
+		DueAt:       &dueAt,
+		Timezone:    todo.Timezone,
+		Recurrence:  todo.Recurrence,
+		Occurrence:  todo.Occurrence + 1,
+	}, nil
+}
+
+// changeStatus moves todo to status when the state machine allows it.
+func changeStatus(todo *entity.Todo, status entity.TodoStatus) error {
+	if !status.IsValid() {
+		validationErr := &ValidationError{}
+		validationErr.Add("status", fmt.Sprintf("must be one of %s", joinStatuses(entity.TodoStatuses())))
+		return validationErr
+	}
+	if !todo.Status.CanTransitionTo(status) {
+		return &InvalidTransitionError{
+			From:    todo.Status,
+			To:      status,
+			Allowed: todo.Status.NextStatuses(),
+		}
+	}
+
+	todo.SetStatus(status, time.Now())
+	return nil
+}
+
+func joinStatuses(statuses []entity.TodoStatus) string {
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+	return strings.Join(names, ", ")
+}
+
+// getForActor loads a todo the actor may access: any todo with anyPermission,
+// only their own with ownPermission.
+func (uc *todoUseCase) getForActor(todoID int, actor *entity.Actor, anyPermission, ownPermission entity.Permission) (*entity.Todo, error) {
+	switch {
+	case actor.Can(anyPermission):
+		return uc.todoRepo.GetByID(todoID)
+	case actor.Can(ownPermission):
+		return uc.todoRepo.GetByIDAndUserID(todoID, actor.UserID)
+	default:
+		return nil, &ForbiddenError{Permission: ownPermission}
+	}
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+
+	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
+	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
+)
+
+// maxCachedUsers bounds the cache so a burst of distinct users cannot grow it
+// without limit.
+const maxCachedUsers = 10000
+
+// UserCache keeps users looked up while verifying tokens for a short time so
+// a burst of requests does not hit the database for every one. A ttl of zero
+// disables caching. Use cases that disable a user, change their role or
+// revoke their tokens invalidate the entry, so the change applies at once in
+// this process; other replicas see it once their entry expires.
+type UserCache struct {
+	userRepo   repository.UserRepository
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[int]cachedUser
+}
+
+type cachedUser struct {
+	user      entity.User
+	expiresAt time.Time
+}
+
+func NewUserCache(userRepo repository.UserRepository, ttl time.Duration) *UserCache {
+	return &UserCache{
+		userRepo:   userRepo,
+		ttl:        ttl,
+		maxEntries: maxCachedUsers,
+		entries:    make(map[int]cachedUser),
+	}
+}
+
+// Get returns a copy of the user, so callers may modify it freely.
+func (c *UserCache) Get(id int) (*entity.User, error) {
+	if c.ttl <= 0 {
+		return c.userRepo.GetByID(id)
+	}
+
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[id]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		user := entry.user
+		return &user, nil
+	}
+
+	user, err := c.userRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if _, ok := c.entries[id]; !ok && len(c.entries) >= c.maxEntries {
+		c.makeRoom(now)
+	}
+	c.entries[id] = cachedUser{user: *user, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return user, nil
+}
+
+// Invalidate drops the cached copy of the user, so the next lookup reads the
+// stored one.
+func (c *UserCache) Invalidate(id int) {
+	c.mu.Lock()
+	delete(c.entries, id)
+	c.mu.Unlock()
+}
+
+// makeRoom drops expired entries, or an arbitrary one when none has expired.
+// The caller holds mu.
+func (c *UserCache) makeRoom(now time.Time) {
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+	if len(c.entries) < c.maxEntries {
+		return
+	}
+	for id := range c.entries {
+		delete(c.entries, id)
+		return
+	}
+}
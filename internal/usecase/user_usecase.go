@@ -2,23 +2,30 @@ package usecase
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/islamyakin/otel-propagation-monorepo/internal/entity"
 	"github.com/islamyakin/otel-propagation-monorepo/internal/repository"
 )
 
 type UserUseCase interface {
-	GetAll() ([]*entity.User, error) // Admin only
+	GetAll() ([]*entity.User, error)
 	GetByID(id int) (*entity.User, error)
+	Disable(actor *entity.Actor, id int) (*entity.User, error)
+	Enable(actor *entity.Actor, id int) (*entity.User, error)
 }
 
 type userUseCase struct {
-	userRepo repository.UserRepository
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	users       *UserCache
 }
 
-func NewUserUseCase(userRepo repository.UserRepository) UserUseCase {
+func NewUserUseCase(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, users *UserCache) UserUseCase {
 	return &userUseCase{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		users:       users,
 	}
 }
 
@@ -46,3 +53,55 @@ func (uc *userUseCase) GetByID(id int) (*entity.User, error) {
 	user.Password = ""
 	return user, nil
 }
+
+// Disable blocks the account from signing in and ends its sessions. Tokens
+// already issued stop working at once on this replica and once the auth user
+// cache expires on the others.
+func (uc *userUseCase) Disable(actor *entity.Actor, id int) (*entity.User, error) {
+	if !actor.Can(entity.PermissionUsersManage) {
+		return nil, &ForbiddenError{Permission: entity.PermissionUsersManage}
+	}
+	if id == actor.UserID {
+		return nil, fmt.Errorf("cannot disable your own account")
+	}
+
+	user, err := uc.userRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.IsDisabled() {
+		user.Password = ""
+		return user, nil
+	}
+
+	now := time.Now()
+	updatedUser, err := uc.userRepo.SetDisabledAt(id, &now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to disable user: %w", err)
+	}
+	uc.users.Invalidate(id)
+
+	if err := uc.sessionRepo.RevokeAllByUserID(id); err != nil {
+		return nil, err
+	}
+
+	// Remove password from response
+	updatedUser.Password = ""
+	return updatedUser, nil
+}
+
+func (uc *userUseCase) Enable(actor *entity.Actor, id int) (*entity.User, error) {
+	if !actor.Can(entity.PermissionUsersManage) {
+		return nil, &ForbiddenError{Permission: entity.PermissionUsersManage}
+	}
+
+	updatedUser, err := uc.userRepo.SetDisabledAt(id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable user: %w", err)
+	}
+	uc.users.Invalidate(id)
+
+	// Remove password from response
+	updatedUser.Password = ""
+	return updatedUser, nil
+}